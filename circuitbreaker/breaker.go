@@ -0,0 +1,230 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_circuitbreaker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// State is the state of a circuit.
+type State int
+
+const (
+	// Closed lets all calls through.
+	Closed State = iota
+	// Open fails all calls fast.
+	Open
+	// HalfOpen lets a limited number of probe calls through.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker keeps the circuits of the calls made through its interceptors.
+//
+// The same CircuitBreaker should be passed to both the unary and stream interceptors of a `grpc.ClientConn`
+// so that they share their circuits.
+type CircuitBreaker struct {
+	opts *options
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// New creates a CircuitBreaker with all its circuits closed.
+func New(opts ...Option) *CircuitBreaker {
+	return &CircuitBreaker{
+		opts:     evaluateOptions(opts),
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// State returns the current state of the circuit of a key.
+func (cb *CircuitBreaker) State(key string) State {
+	c := cb.circuitFor(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshLocked(cb.opts.clock.Now())
+	return c.state
+}
+
+func (cb *CircuitBreaker) circuitFor(key string) *circuit {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		c = &circuit{breaker: cb, key: key}
+		cb.circuits[key] = c
+	}
+	return c
+}
+
+// allow returns whether a call can go through the circuit of key.
+func (cb *CircuitBreaker) allow(key string) bool {
+	c := cb.circuitFor(key)
+	c.mu.Lock()
+	now := cb.opts.clock.Now()
+	c.refreshLocked(now)
+	allowed := true
+	switch c.state {
+	case Open:
+		allowed = false
+	case HalfOpen:
+		if c.probes < cb.opts.halfOpenRequests {
+			c.probes++
+		} else if now.Sub(c.changedAt) >= cb.opts.openTimeout {
+			// The probes never reported back (e.g. abandoned streams), let new ones through.
+			c.changedAt = now
+			c.probes = 1
+			c.successes = 0
+		} else {
+			allowed = false
+		}
+	}
+	c.mu.Unlock()
+	c.notify()
+	return allowed
+}
+
+// record accounts for the outcome of a call that went through the circuit of key.
+func (cb *CircuitBreaker) record(ctx context.Context, key string, err error) {
+	if err != nil && ctx.Err() == context.Canceled {
+		// The caller gave up on the call, which says nothing about the backend.
+		return
+	}
+	failed := cb.isFailure(err)
+	c := cb.circuitFor(key)
+	c.mu.Lock()
+	now := cb.opts.clock.Now()
+	c.refreshLocked(now)
+	switch c.state {
+	case Closed:
+		if now.Sub(c.windowStart) >= c.opts().window {
+			c.windowStart = now
+			c.requests = 0
+			c.failures = 0
+		}
+		c.requests++
+		if failed {
+			c.failures++
+			c.consecutiveFailures++
+		} else {
+			c.consecutiveFailures = 0
+		}
+		if c.shouldTripLocked() {
+			c.setStateLocked(Open, now)
+		}
+	case HalfOpen:
+		if failed {
+			c.setStateLocked(Open, now)
+		} else if c.successes++; c.successes >= cb.opts.halfOpenRequests {
+			c.setStateLocked(Closed, now)
+		}
+	}
+	c.mu.Unlock()
+	c.notify()
+}
+
+func (cb *CircuitBreaker) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	code := status.Code(err)
+	for _, c := range cb.opts.failureCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// circuit is the state of the calls sharing the same key.
+type circuit struct {
+	breaker *CircuitBreaker
+	key     string
+
+	mu                  sync.Mutex
+	state               State
+	changedAt           time.Time
+	consecutiveFailures uint
+	windowStart         time.Time
+	requests            uint
+	failures            uint
+	probes              uint
+	successes           uint
+	transitions         []transition // state changes not notified yet
+}
+
+type transition struct {
+	from State
+	to   State
+}
+
+func (c *circuit) opts() *options {
+	return c.breaker.opts
+}
+
+// refreshLocked moves an open circuit to half-open once its timeout has elapsed.
+func (c *circuit) refreshLocked(now time.Time) {
+	if c.state == Open && now.Sub(c.changedAt) >= c.opts().openTimeout {
+		c.setStateLocked(HalfOpen, now)
+	}
+}
+
+func (c *circuit) shouldTripLocked() bool {
+	o := c.opts()
+	if o.consecutiveFailures > 0 && c.consecutiveFailures >= o.consecutiveFailures {
+		return true
+	}
+	if o.failureRate > 0 && c.requests > 0 && c.requests >= o.minRequests {
+		return float64(c.failures)/float64(c.requests) >= o.failureRate
+	}
+	return false
+}
+
+func (c *circuit) setStateLocked(state State, now time.Time) {
+	c.transitions = append(c.transitions, transition{from: c.state, to: state})
+	c.state = state
+	c.changedAt = now
+	c.consecutiveFailures = 0
+	c.windowStart = now
+	c.requests = 0
+	c.failures = 0
+	c.probes = 0
+	c.successes = 0
+}
+
+// notify calls the state change callback for the transitions that happened, outside of the lock.
+func (c *circuit) notify() {
+	c.mu.Lock()
+	transitions := c.transitions
+	c.transitions = nil
+	c.mu.Unlock()
+	if c.opts().onStateChange == nil {
+		return
+	}
+	for _, t := range transitions {
+		c.opts().onStateChange(c.key, t.from, t.to)
+	}
+}
+
+func errCircuitOpen(method string) error {
+	return status.Errorf(codes.Unavailable, "%s is rejected by grpc_circuitbreaker middleware, the circuit is open.", method)
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_circuitbreaker
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryClientInterceptor returns a new unary client interceptor that fails calls fast while their circuit is open.
+func UnaryClientInterceptor(cb *CircuitBreaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		key := cb.opts.keyFunc(cc, method)
+		if !cb.allow(key) {
+			return errCircuitOpen(method)
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		cb.record(ctx, key, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a new stream client interceptor that fails calls fast while their circuit is open.
+//
+// The outcome of a stream is the error it is established with, or the error its RecvMsg() finishes with. Streams
+// without server streaming succeed as soon as their single response is received.
+func StreamClientInterceptor(cb *CircuitBreaker) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		key := cb.opts.keyFunc(cc, method)
+		if !cb.allow(key) {
+			return nil, errCircuitOpen(method)
+		}
+		clientStream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cb.record(ctx, key, err)
+			return nil, err
+		}
+		return &monitoredClientStream{ClientStream: clientStream, ctx: ctx, breaker: cb, key: key, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// monitoredClientStream records the outcome of a stream in its circuit once it is finished.
+type monitoredClientStream struct {
+	grpc.ClientStream
+	ctx           context.Context
+	breaker       *CircuitBreaker
+	key           string
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *monitoredClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF || (err == nil && !s.serverStreams) {
+		s.once.Do(func() { s.breaker.record(s.ctx, s.key, nil) })
+	} else if err != nil {
+		s.once.Do(func() { s.breaker.record(s.ctx, s.key, err) })
+	}
+	return err
+}
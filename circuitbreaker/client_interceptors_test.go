@@ -0,0 +1,266 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_circuitbreaker_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	grpc_circuitbreaker "github.com/grpc-ecosystem/go-grpc-middleware/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testMethod = "/mwitkow.testproto.TestService/Ping"
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+type fakeBackend struct {
+	code  codes.Code
+	calls int
+}
+
+func (b *fakeBackend) invoke(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+	b.calls++
+	return status.Error(b.code, "fake backend error")
+}
+
+func (b *fakeBackend) call(t *testing.T, interceptor grpc.UnaryClientInterceptor, method string) error {
+	return interceptor(context.Background(), method, nil, nil, nil, b.invoke)
+}
+
+func TestUnaryClientInterceptor_TripsOnConsecutiveFailures(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cb := grpc_circuitbreaker.New(grpc_circuitbreaker.WithConsecutiveFailures(3), grpc_circuitbreaker.WithClock(clock))
+	interceptor := grpc_circuitbreaker.UnaryClientInterceptor(cb)
+	backend := &fakeBackend{code: codes.Unavailable}
+	key := grpc_circuitbreaker.KeyByMethod(nil, testMethod)
+
+	for i := 0; i < 3; i++ {
+		err := backend.call(t, interceptor, testMethod)
+		assert.Equal(t, codes.Unavailable, status.Code(err), "errors of the backend must be returned")
+	}
+	require.Equal(t, grpc_circuitbreaker.Open, cb.State(key), "the circuit must open after 3 failures")
+
+	err := backend.call(t, interceptor, testMethod)
+	assert.Equal(t, codes.Unavailable, status.Code(err), "calls must fail fast with Unavailable")
+	assert.Contains(t, err.Error(), "circuit is open")
+	assert.Equal(t, 3, backend.calls, "calls must not reach the backend while the circuit is open")
+}
+
+func TestUnaryClientInterceptor_SuccessResetsConsecutiveFailures(t *testing.T) {
+	cb := grpc_circuitbreaker.New(grpc_circuitbreaker.WithConsecutiveFailures(2))
+	interceptor := grpc_circuitbreaker.UnaryClientInterceptor(cb)
+	backend := &fakeBackend{}
+	for i := 0; i < 10; i++ {
+		backend.code = codes.Unavailable
+		backend.call(t, interceptor, testMethod)
+		backend.code = codes.NotFound // not a failure of the backend
+		backend.call(t, interceptor, testMethod)
+	}
+	assert.Equal(t, grpc_circuitbreaker.Closed, cb.State(grpc_circuitbreaker.KeyByMethod(nil, testMethod)))
+	assert.Equal(t, 20, backend.calls, "all calls must reach the backend")
+}
+
+func TestUnaryClientInterceptor_HalfOpenProbes(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	var transitions []string
+	cb := grpc_circuitbreaker.New(
+		grpc_circuitbreaker.WithConsecutiveFailures(1),
+		grpc_circuitbreaker.WithOpenTimeout(time.Second),
+		grpc_circuitbreaker.WithHalfOpenRequests(2),
+		grpc_circuitbreaker.WithClock(clock),
+		grpc_circuitbreaker.WithStateChangeCallback(func(key string, from, to grpc_circuitbreaker.State) {
+			transitions = append(transitions, fmt.Sprintf("%s: %v -> %v", key, from, to))
+		}),
+	)
+	interceptor := grpc_circuitbreaker.UnaryClientInterceptor(cb)
+	backend := &fakeBackend{code: codes.Unavailable}
+
+	backend.call(t, interceptor, testMethod)
+	clock.Advance(time.Second)
+	backend.call(t, interceptor, testMethod) // failed probe
+	require.Equal(t, 2, backend.calls, "a probe must be let through once the timeout elapsed")
+
+	clock.Advance(time.Second)
+	backend.code = codes.OK
+	backend.call(t, interceptor, testMethod)
+	backend.call(t, interceptor, testMethod)
+	backend.call(t, interceptor, testMethod)
+	assert.Equal(t, 5, backend.calls, "all calls must go through once the circuit closed")
+
+	assert.Equal(t, []string{
+		testMethod + ": closed -> open",
+		testMethod + ": open -> half-open",
+		testMethod + ": half-open -> open",
+		testMethod + ": open -> half-open",
+		testMethod + ": half-open -> closed",
+	}, transitions)
+}
+
+func TestUnaryClientInterceptor_HalfOpenLimitsProbes(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cb := grpc_circuitbreaker.New(grpc_circuitbreaker.WithConsecutiveFailures(1), grpc_circuitbreaker.WithOpenTimeout(time.Second), grpc_circuitbreaker.WithClock(clock))
+	interceptor := grpc_circuitbreaker.UnaryClientInterceptor(cb)
+	blocked := 0
+	backend := &fakeBackend{code: codes.Unavailable}
+	backend.call(t, interceptor, testMethod)
+	clock.Advance(time.Second)
+	// The probe is still in flight while other calls are made.
+	probe := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if err := interceptor(ctx, method, nil, nil, nil, backend.invoke); err != nil && status.Code(err) == codes.Unavailable && backend.calls == 1 {
+			blocked++
+		}
+		return nil
+	}
+	require.NoError(t, interceptor(context.Background(), testMethod, nil, nil, nil, probe))
+	assert.Equal(t, 1, blocked, "only one probe must be let through")
+	assert.Equal(t, grpc_circuitbreaker.Closed, cb.State(grpc_circuitbreaker.KeyByMethod(nil, testMethod)), "a successful probe must close the circuit")
+}
+
+func TestUnaryClientInterceptor_TripsOnFailureRate(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cb := grpc_circuitbreaker.New(
+		grpc_circuitbreaker.WithConsecutiveFailures(0),
+		grpc_circuitbreaker.WithFailureRate(0.5, 10, time.Minute),
+		grpc_circuitbreaker.WithClock(clock),
+	)
+	interceptor := grpc_circuitbreaker.UnaryClientInterceptor(cb)
+	backend := &fakeBackend{}
+	key := grpc_circuitbreaker.KeyByMethod(nil, testMethod)
+	for i := 0; i < 10; i++ {
+		backend.code = codes.Unavailable
+		backend.call(t, interceptor, testMethod)
+		backend.code = codes.OK
+		backend.call(t, interceptor, testMethod)
+		if i == 4 {
+			require.Equal(t, grpc_circuitbreaker.Open, cb.State(key), "the circuit must open once 10 calls were made")
+			clock.Advance(time.Hour)
+			require.Equal(t, grpc_circuitbreaker.HalfOpen, cb.State(key))
+			backend.call(t, interceptor, testMethod)
+			require.Equal(t, grpc_circuitbreaker.Closed, cb.State(key))
+			clock.Advance(time.Minute)
+		}
+	}
+	assert.Equal(t, grpc_circuitbreaker.Open, cb.State(key), "the circuit must open when half of the calls fail")
+}
+
+func TestUnaryClientInterceptor_FailureRateWithoutWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cb := grpc_circuitbreaker.New(
+		grpc_circuitbreaker.WithConsecutiveFailures(0),
+		grpc_circuitbreaker.WithFailureRate(0.5, 10, 0),
+		grpc_circuitbreaker.WithClock(clock),
+	)
+	interceptor := grpc_circuitbreaker.UnaryClientInterceptor(cb)
+	backend := &fakeBackend{code: codes.OK}
+	key := grpc_circuitbreaker.KeyByMethod(nil, testMethod)
+	for i := 0; i < 1000; i++ {
+		backend.call(t, interceptor, testMethod)
+	}
+	clock.Advance(grpc_circuitbreaker.DefaultFailureRateWindow)
+
+	backend.code = codes.Unavailable
+	for i := 0; i < 10; i++ {
+		backend.call(t, interceptor, testMethod)
+	}
+	assert.Equal(t, grpc_circuitbreaker.Open, cb.State(key), "the failures of an outage must not be diluted in the calls of a previous window")
+}
+
+func TestUnaryClientInterceptor_IgnoresCallerCancellation(t *testing.T) {
+	cb := grpc_circuitbreaker.New(grpc_circuitbreaker.WithConsecutiveFailures(1))
+	interceptor := grpc_circuitbreaker.UnaryClientInterceptor(cb)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	backend := &fakeBackend{code: codes.Unknown}
+	interceptor(ctx, testMethod, nil, nil, nil, backend.invoke)
+	assert.Equal(t, grpc_circuitbreaker.Closed, cb.State(grpc_circuitbreaker.KeyByMethod(nil, testMethod)))
+}
+
+func TestUnaryClientInterceptor_KeyByTarget(t *testing.T) {
+	cb := grpc_circuitbreaker.New(grpc_circuitbreaker.WithConsecutiveFailures(1), grpc_circuitbreaker.WithKeyFunc(grpc_circuitbreaker.KeyByTarget))
+	interceptor := grpc_circuitbreaker.UnaryClientInterceptor(cb)
+	backend := &fakeBackend{code: codes.Unavailable}
+	backend.call(t, interceptor, testMethod)
+	backend.call(t, interceptor, "/mwitkow.testproto.TestService/PingList")
+	assert.Equal(t, 1, backend.calls, "all methods of the target must share the circuit")
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	err error
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	return s.err
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	cb := grpc_circuitbreaker.New(grpc_circuitbreaker.WithConsecutiveFailures(2))
+	interceptor := grpc_circuitbreaker.StreamClientInterceptor(cb)
+	key := grpc_circuitbreaker.KeyByMethod(nil, testMethod)
+	streams := 0
+	streamerFailingWith := func(recvErr error) grpc.Streamer {
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			streams++
+			return &fakeClientStream{err: recvErr}, nil
+		}
+	}
+
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil, testMethod, streamerFailingWith(io.EOF))
+	require.NoError(t, err)
+	assert.Equal(t, io.EOF, stream.RecvMsg(nil), "the stream must be proxied")
+
+	for i := 0; i < 2; i++ {
+		stream, err := interceptor(context.Background(), &grpc.StreamDesc{}, nil, testMethod, streamerFailingWith(status.Error(codes.Unavailable, "broken")))
+		require.NoError(t, err)
+		stream.RecvMsg(nil)
+		stream.RecvMsg(nil) // only the first error of a stream counts
+		if i == 0 {
+			assert.Equal(t, grpc_circuitbreaker.Closed, cb.State(key))
+		}
+	}
+	assert.Equal(t, grpc_circuitbreaker.Open, cb.State(key), "failed streams must trip the circuit")
+
+	_, err = interceptor(context.Background(), &grpc.StreamDesc{}, nil, testMethod, streamerFailingWith(io.EOF))
+	assert.Equal(t, codes.Unavailable, status.Code(err), "streams must fail fast while the circuit is open")
+	assert.Equal(t, 3, streams, "streams must not be established while the circuit is open")
+}
+
+func TestStreamClientInterceptor_SingleResponseSucceeds(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	cb := grpc_circuitbreaker.New(grpc_circuitbreaker.WithConsecutiveFailures(1), grpc_circuitbreaker.WithOpenTimeout(time.Second), grpc_circuitbreaker.WithClock(clock))
+	interceptor := grpc_circuitbreaker.StreamClientInterceptor(cb)
+	key := grpc_circuitbreaker.KeyByMethod(nil, testMethod)
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{}, nil
+	}
+
+	backend := &fakeBackend{code: codes.Unavailable}
+	backend.call(t, grpc_circuitbreaker.UnaryClientInterceptor(cb), testMethod)
+	require.Equal(t, grpc_circuitbreaker.Open, cb.State(key))
+	clock.Advance(time.Second)
+
+	clientStreaming := &grpc.StreamDesc{ClientStreams: true}
+	stream, err := interceptor(context.Background(), clientStreaming, nil, testMethod, streamer)
+	require.NoError(t, err, "the probe must be let through once the timeout elapsed")
+	require.NoError(t, stream.RecvMsg(nil), "the response must be received")
+	assert.Equal(t, grpc_circuitbreaker.Closed, cb.State(key), "the response of a client stream must report the probe as successful")
+}
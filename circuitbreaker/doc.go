@@ -0,0 +1,23 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_circuitbreaker` provides client-side circuit breaking for gRPC.
+
+Client-Side Circuit Breaker Interceptor
+
+A `CircuitBreaker` tracks the outcome of the calls made through its interceptors, per method or per
+target. While its circuit is closed, calls go through. Once too many of them fail (with codes such as
+`Unavailable` or `DeadlineExceeded`), the circuit opens and calls fail fast with `Unavailable`, without
+reaching the backend. After a timeout the circuit is half-open: a few probe calls are let through, and
+their outcome decides whether the circuit closes again or reopens.
+
+The circuit trips after 5 consecutive failures by default. A failure rate can be used instead (or as
+well) through `WithFailureRate`.
+
+When used together with `grpc_retry`, the circuit breaker should be chained after the retry interceptor,
+so that every attempt is accounted for, and so that fast-failed attempts are not retried needlessly.
+
+Please see examples for simple examples of use.
+*/
+package grpc_circuitbreaker
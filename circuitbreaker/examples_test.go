@@ -0,0 +1,49 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_circuitbreaker_test
+
+import (
+	"log"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/circuitbreaker"
+	"github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"google.golang.org/grpc"
+)
+
+// Simple example of client initialization code, with circuits kept per method.
+func Example_initialization() {
+	cb := grpc_circuitbreaker.New(
+		grpc_circuitbreaker.WithConsecutiveFailures(10),
+		grpc_circuitbreaker.WithOpenTimeout(30*time.Second),
+		grpc_circuitbreaker.WithStateChangeCallback(func(key string, from, to grpc_circuitbreaker.State) {
+			log.Printf("circuit of %s went from %v to %v", key, from, to)
+		}),
+	)
+	grpc.Dial("myservice.example.com",
+		grpc.WithUnaryInterceptor(grpc_circuitbreaker.UnaryClientInterceptor(cb)),
+		grpc.WithStreamInterceptor(grpc_circuitbreaker.StreamClientInterceptor(cb)),
+	)
+}
+
+// Example of a circuit breaker shared by all methods of the target, tripping when half of the calls
+// fail, and chained after the retry interceptor.
+func Example_initializationWithRetries() {
+	cb := grpc_circuitbreaker.New(
+		grpc_circuitbreaker.WithKeyFunc(grpc_circuitbreaker.KeyByTarget),
+		grpc_circuitbreaker.WithConsecutiveFailures(0),
+		grpc_circuitbreaker.WithFailureRate(0.5, 20, time.Minute),
+	)
+	grpc.Dial("myservice.example.com",
+		grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
+			grpc_retry.UnaryClientInterceptor(grpc_retry.WithMax(3)),
+			grpc_circuitbreaker.UnaryClientInterceptor(cb),
+		)),
+		grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
+			grpc_retry.StreamClientInterceptor(grpc_retry.WithMax(3)),
+			grpc_circuitbreaker.StreamClientInterceptor(cb),
+		)),
+	)
+}
@@ -0,0 +1,150 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_circuitbreaker
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	// DefaultFailureCodes is the set of gRPC codes that are considered a failure of the backend.
+	DefaultFailureCodes = []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown}
+
+	defaultOptions = &options{
+		failureCodes:        DefaultFailureCodes,
+		consecutiveFailures: 5,
+		openTimeout:         10 * time.Second,
+		halfOpenRequests:    1,
+		keyFunc:             KeyByMethod,
+		clock:               realClock{},
+	}
+)
+
+// KeyFunc returns the key of the circuit a call belongs to.
+type KeyFunc func(cc *grpc.ClientConn, method string) string
+
+// StateChangeFunc is called whenever the circuit of a key changes state.
+type StateChangeFunc func(key string, from State, to State)
+
+// Clock tells the current time to the CircuitBreaker. It can be replaced in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// KeyByMethod keeps a circuit per method of each target.
+func KeyByMethod(cc *grpc.ClientConn, method string) string {
+	return KeyByTarget(cc, method) + method
+}
+
+// KeyByTarget keeps a single circuit per target, shared by all its methods.
+func KeyByTarget(cc *grpc.ClientConn, method string) string {
+	if cc == nil {
+		return ""
+	}
+	return cc.Target()
+}
+
+type options struct {
+	failureCodes        []codes.Code
+	consecutiveFailures uint
+	failureRate         float64
+	minRequests         uint
+	window              time.Duration
+	openTimeout         time.Duration
+	halfOpenRequests    uint
+	keyFunc             KeyFunc
+	onStateChange       StateChangeFunc
+	clock               Clock
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithFailureCodes sets which codes count as failures. Calls failing with other codes count as successes,
+// as the backend did respond.
+func WithFailureCodes(failureCodes ...codes.Code) Option {
+	return func(o *options) {
+		o.failureCodes = failureCodes
+	}
+}
+
+// WithConsecutiveFailures sets the number of consecutive failures that trips the circuit. 0 disables
+// this condition.
+func WithConsecutiveFailures(n uint) Option {
+	return func(o *options) {
+		o.consecutiveFailures = n
+	}
+}
+
+// DefaultFailureRateWindow is the window over which calls are counted when WithFailureRate isn't given one.
+const DefaultFailureRateWindow = time.Minute
+
+// WithFailureRate trips the circuit when the proportion of failed calls reaches rate (between 0 and 1),
+// provided that at least minRequests calls were made. Calls are counted over consecutive windows of
+// the given duration (DefaultFailureRateWindow if 0), so that the failures of an outage aren't diluted in
+// the calls of a long healthy run.
+func WithFailureRate(rate float64, minRequests uint, window time.Duration) Option {
+	return func(o *options) {
+		o.failureRate = rate
+		o.minRequests = minRequests
+		o.window = window
+		if window <= 0 {
+			o.window = DefaultFailureRateWindow
+		}
+	}
+}
+
+// WithOpenTimeout sets how long the circuit stays open before letting probe calls through.
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openTimeout = timeout
+	}
+}
+
+// WithHalfOpenRequests sets the number of probe calls let through while the circuit is half-open. The
+// circuit closes when all of them succeed, and reopens as soon as one fails.
+func WithHalfOpenRequests(n uint) Option {
+	return func(o *options) {
+		o.halfOpenRequests = n
+	}
+}
+
+// WithKeyFunc sets how calls are grouped into circuits, e.g. `KeyByMethod` (the default) or `KeyByTarget`.
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithStateChangeCallback sets a function called whenever a circuit changes state, e.g. to log or
+// record metrics.
+func WithStateChangeCallback(f StateChangeFunc) Option {
+	return func(o *options) {
+		o.onStateChange = f
+	}
+}
+
+// WithClock sets the Clock used to time the open state of circuits and the failure rate windows.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_idempotency` deduplicates unary calls carrying the same idempotency key.
+
+Server-Side Deduplication Interceptor
+
+Retrying a call (e.g. with `grpc_retry`) is only safe if the call is idempotent: a write whose response was
+lost may otherwise be applied twice. The server interceptor reads an idempotency key from the
+`x-idempotency-key` metadata of the call, and keeps the response of the first successful call with that
+key in a `Store`. Later calls with the same key get the stored response without reaching the handler, and
+calls made while the first one is still being handled fail with `Aborted`. Failed calls are not stored,
+so that they can be retried.
+
+Keys are scoped to the method they are used with, and expire after a TTL. A key reused with a different request
+fails with `InvalidArgument` instead of getting the response of the first one. Keys are not scoped to callers by
+default: a caller sending the key and the request of another caller gets the response stored for them. Servers
+whose responses must not be shared between callers should scope keys by the identity of the caller with
+`WithKeyFunc`.
+
+Client-Side Key Interceptor
+
+The client interceptor adds a random idempotency key to the calls that don't have one yet. It has to be
+chained *before* the retry interceptor, so that all the attempts of a call share the same key, the same way
+they share the `x-retry-attempty` header.
+
+Please see examples for simple examples of use.
+*/
+package grpc_idempotency
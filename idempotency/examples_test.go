@@ -0,0 +1,32 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_idempotency_test
+
+import (
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/idempotency"
+	"github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"google.golang.org/grpc"
+)
+
+// Simple example of server initialization code, keeping responses in memory for 10 minutes.
+func Example_serverInitialization() {
+	_ = grpc.NewServer(
+		grpc_middleware.WithUnaryServerChain(
+			grpc_idempotency.UnaryServerInterceptor(grpc_idempotency.NewMemoryStore(), grpc_idempotency.WithTTL(10*time.Minute)),
+		),
+	)
+}
+
+// Example of client initialization code, where the retried attempts of a call share its idempotency key.
+func Example_clientInitialization() {
+	grpc.Dial("myservice.example.com",
+		grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
+			grpc_idempotency.UnaryClientInterceptor(),
+			grpc_retry.UnaryClientInterceptor(grpc_retry.WithMax(3)),
+		)),
+	)
+}
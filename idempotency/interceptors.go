@@ -0,0 +1,121 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MetadataKey is the metadata carrying the idempotency key of a call.
+	MetadataKey = "x-idempotency-key"
+)
+
+// UnaryServerInterceptor returns a new unary server interceptor that deduplicates calls with the same idempotency key.
+//
+// Only responses that are protobuf messages are stored, calls returning anything else are not deduplicated. A key
+// reused with a different request fails with `InvalidArgument`.
+func UnaryServerInterceptor(store Store, opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		idempotencyKey := metautils.ExtractIncoming(ctx).Get(MetadataKey)
+		if idempotencyKey == "" {
+			return handler(ctx, req)
+		}
+		key := o.keyFunc(ctx, info.FullMethod, idempotencyKey)
+		reqHash, err := requestHash(req)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "grpc_idempotency: failed hashing request: %v", err)
+		}
+		entry, err := store.Reserve(ctx, key, reqHash, o.inProgressTTL)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "grpc_idempotency: failed reserving idempotency key: %v", err)
+		}
+		if entry != nil {
+			if !bytes.Equal(entry.RequestHash, reqHash) {
+				return nil, status.Errorf(codes.InvalidArgument, "the idempotency key was used with a different request")
+			}
+			return storedResponse(entry)
+		}
+		resp, err := handler(ctx, req)
+		msg, isProto := resp.(proto.Message)
+		if err != nil || !isProto {
+			release(store, key, o)
+			return resp, err
+		}
+		anyResp, err := ptypes.MarshalAny(msg)
+		if err == nil {
+			err = store.Save(ctx, key, reqHash, anyResp, o.ttl)
+		}
+		if err != nil {
+			grpclog.Warningf("grpc_idempotency: failed saving response: %v", err)
+			release(store, key, o)
+		}
+		return resp, nil
+	}
+}
+
+// release frees the reservation of a key whose response isn't stored. It doesn't use the context of the call, which
+// is usually done when the call failed, so that the key isn't left reserved until its in-progress TTL expires.
+func release(store Store, key string, o *options) {
+	ctx, cancel := context.WithTimeout(context.Background(), o.releaseTimeout)
+	defer cancel()
+	if err := store.Release(ctx, key); err != nil {
+		grpclog.Warningf("grpc_idempotency: failed releasing idempotency key: %v", err)
+	}
+}
+
+// requestHash returns the SHA-256 of the deterministic serialization of a protobuf request, or nil if the request
+// isn't a protobuf message.
+func requestHash(req interface{}) ([]byte, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil, nil
+	}
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(msg); err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(buf.Bytes())
+	return hash[:], nil
+}
+
+func storedResponse(entry *Entry) (interface{}, error) {
+	if entry.InProgress || entry.Response == nil {
+		return nil, status.Errorf(codes.Aborted, "a call with the same idempotency key is in progress")
+	}
+	resp, err := ptypes.Empty(entry.Response)
+	if err == nil {
+		err = ptypes.UnmarshalAny(entry.Response, resp)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "grpc_idempotency: failed reading stored response: %v", err)
+	}
+	return resp, nil
+}
+
+// UnaryClientInterceptor returns a new unary client interceptor that adds an idempotency key to outgoing calls.
+//
+// Calls that already have an idempotency key in their outgoing metadata keep it.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		md := metautils.ExtractOutgoing(ctx)
+		if md.Get(MetadataKey) == "" {
+			ctx = md.Clone().Set(MetadataKey, o.keyGenerator()).ToOutgoing(ctx)
+		}
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_idempotency_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_idempotency "github.com/grpc-ecosystem/go-grpc-middleware/idempotency"
+	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	grpc_testing "github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var goodPing = &pb_testproto.PingRequest{Value: "something"}
+
+// countingService counts the calls reaching Ping, and can fail or block them.
+type countingService struct {
+	pb_testproto.TestServiceServer
+
+	mu       sync.Mutex
+	calls    int
+	keys     []string
+	failNext bool
+	block    chan struct{}
+}
+
+func (s *countingService) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = 0
+	s.keys = nil
+	s.failNext = false
+	s.block = nil
+}
+
+func (s *countingService) Ping(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
+	s.mu.Lock()
+	s.calls++
+	calls := s.calls
+	s.keys = append(s.keys, metautils.ExtractIncoming(ctx).Get(grpc_idempotency.MetadataKey))
+	fail := s.failNext
+	s.failNext = false
+	block := s.block
+	s.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	if fail {
+		return nil, status.Error(codes.Unavailable, "failing once")
+	}
+	return &pb_testproto.PingResponse{Value: ping.Value, Counter: int32(calls)}, nil
+}
+
+func TestIdempotencySuite(t *testing.T) {
+	service := &countingService{TestServiceServer: &grpc_testing.TestPingService{T: t}}
+	s := &IdempotencySuite{
+		srv: service,
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: service,
+			ServerOpts: []grpc.ServerOption{
+				grpc_middleware.WithUnaryServerChain(grpc_idempotency.UnaryServerInterceptor(grpc_idempotency.NewMemoryStore())),
+			},
+			ClientOpts: []grpc.DialOption{
+				grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(
+					grpc_idempotency.UnaryClientInterceptor(),
+					grpc_retry.UnaryClientInterceptor(grpc_retry.WithBackoff(grpc_retry.BackoffLinear(time.Millisecond))),
+				)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type IdempotencySuite struct {
+	*grpc_testing.InterceptorTestSuite
+	srv *countingService
+}
+
+func (s *IdempotencySuite) SetupTest() {
+	s.srv.reset()
+}
+
+func (s *IdempotencySuite) ctxWithKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(s.SimpleCtx(), grpc_idempotency.MetadataKey, key)
+}
+
+func (s *IdempotencySuite) TestDuplicateCallGetsStoredResponse() {
+	first, err := s.Client.Ping(s.ctxWithKey("duplicate"), goodPing)
+	require.NoError(s.T(), err)
+	second, err := s.Client.Ping(s.ctxWithKey("duplicate"), goodPing)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1, s.srv.calls, "the duplicate call must not reach the handler")
+	assert.Equal(s.T(), first.Counter, second.Counter, "the stored response must be returned")
+	assert.Equal(s.T(), first.Value, second.Value, "the stored response must be returned")
+}
+
+func (s *IdempotencySuite) TestDifferentKeysAreNotDeduplicated() {
+	_, err := s.Client.Ping(s.ctxWithKey("first"), goodPing)
+	require.NoError(s.T(), err)
+	_, err = s.Client.Ping(s.ctxWithKey("second"), goodPing)
+	require.NoError(s.T(), err)
+	_, err = s.Client.PingEmpty(s.ctxWithKey("first"), &pb_testproto.Empty{})
+	require.NoError(s.T(), err, "keys must be scoped to their method")
+	assert.Equal(s.T(), 2, s.srv.calls)
+}
+
+func (s *IdempotencySuite) TestKeyReusedWithDifferentRequestIsRejected() {
+	_, err := s.Client.Ping(s.ctxWithKey("reused"), goodPing)
+	require.NoError(s.T(), err)
+	_, err = s.Client.Ping(s.ctxWithKey("reused"), &pb_testproto.PingRequest{Value: "something else"})
+	assert.Equal(s.T(), codes.InvalidArgument, status.Code(err), "a different request must not get the stored response")
+	assert.Equal(s.T(), 1, s.srv.calls, "the different request must not reach the handler")
+}
+
+func (s *IdempotencySuite) TestFailedCallsAreNotStored() {
+	s.srv.failNext = true
+	_, err := s.Client.Ping(s.ctxWithKey("failing"), goodPing)
+	require.Equal(s.T(), codes.Unavailable, status.Code(err))
+	_, err = s.Client.Ping(s.ctxWithKey("failing"), goodPing)
+	require.NoError(s.T(), err, "the call must be made again after a failure")
+	assert.Equal(s.T(), 2, s.srv.calls)
+}
+
+func (s *IdempotencySuite) TestConcurrentDuplicateIsAborted() {
+	block := make(chan struct{})
+	s.srv.block = block
+	done := make(chan error)
+	go func() {
+		_, err := s.Client.Ping(s.ctxWithKey("concurrent"), goodPing)
+		done <- err
+	}()
+	require.Eventually(s.T(), func() bool {
+		s.srv.mu.Lock()
+		defer s.srv.mu.Unlock()
+		return s.srv.calls == 1
+	}, time.Second, time.Millisecond, "the first call must reach the handler")
+	_, err := s.Client.Ping(s.ctxWithKey("concurrent"), goodPing)
+	assert.Equal(s.T(), codes.Aborted, status.Code(err), "a concurrent duplicate must be aborted")
+	close(block)
+	require.NoError(s.T(), <-done)
+}
+
+func (s *IdempotencySuite) TestClientKeyIsSharedByRetries() {
+	s.srv.failNext = true
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithMax(2))
+	require.NoError(s.T(), err, "the retry must succeed")
+	require.Len(s.T(), s.srv.keys, 2, "two attempts should have been made")
+	assert.NotEmpty(s.T(), s.srv.keys[0], "the client interceptor must add a key")
+	assert.Equal(s.T(), s.srv.keys[0], s.srv.keys[1], "the attempts must share the same key")
+
+	_, err = s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err)
+	assert.NotEqual(s.T(), s.srv.keys[0], s.srv.keys[2], "every call must get its own key")
+}
+
+func callerKey(ctx context.Context, fullMethod string, idempotencyKey string) string {
+	return metautils.ExtractIncoming(ctx).Get("x-caller") + "/" + grpc_idempotency.MethodKey(ctx, fullMethod, idempotencyKey)
+}
+
+func TestKeyFuncScopesKeys(t *testing.T) {
+	interceptor := grpc_idempotency.UnaryServerInterceptor(grpc_idempotency.NewMemoryStore(), grpc_idempotency.WithKeyFunc(callerKey))
+	info := &grpc.UnaryServerInfo{FullMethod: "/mwitkow.testproto.TestService/Ping"}
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return &pb_testproto.PingResponse{Counter: int32(calls)}, nil
+	}
+	call := func(caller string) *pb_testproto.PingResponse {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-caller", caller, grpc_idempotency.MetadataKey, "key"))
+		resp, err := interceptor(ctx, goodPing, info, handler)
+		require.NoError(t, err)
+		return resp.(*pb_testproto.PingResponse)
+	}
+
+	first := call("alice")
+	assert.Equal(t, first.Counter, call("alice").Counter, "the same caller must get the stored response")
+	assert.NotEqual(t, first.Counter, call("bob").Counter, "another caller must not get the stored response")
+	assert.Equal(t, 2, calls)
+}
+
+// releaseRecordingStore records the context of the calls to Release.
+type releaseRecordingStore struct {
+	*grpc_idempotency.MemoryStore
+	releaseCtx context.Context
+}
+
+func (s *releaseRecordingStore) Release(ctx context.Context, key string) error {
+	s.releaseCtx = ctx
+	return s.MemoryStore.Release(ctx, key)
+}
+
+func TestReleaseOutlivesTheCall(t *testing.T) {
+	store := &releaseRecordingStore{MemoryStore: grpc_idempotency.NewMemoryStore()}
+	interceptor := grpc_idempotency.UnaryServerInterceptor(store)
+	info := &grpc.UnaryServerInfo{FullMethod: "/mwitkow.testproto.TestService/Ping"}
+	ctx, cancel := context.WithCancel(metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpc_idempotency.MetadataKey, "key")))
+	_, err := interceptor(ctx, goodPing, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		cancel()
+		return nil, status.Error(codes.Canceled, "the call was cancelled")
+	})
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	require.NotNil(t, store.releaseCtx, "the key must be released")
+	assert.NotEqual(t, ctx, store.releaseCtx, "the key must not be released with the context of the call")
+	_, hasDeadline := store.releaseCtx.Deadline()
+	assert.True(t, hasDeadline, "releasing the key must have its own timeout")
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+var (
+	defaultOptions = &options{
+		ttl:            time.Hour,
+		inProgressTTL:  time.Minute,
+		releaseTimeout: 5 * time.Second,
+		keyFunc:        MethodKey,
+		keyGenerator:   RandomKey,
+	}
+)
+
+type options struct {
+	ttl            time.Duration
+	inProgressTTL  time.Duration
+	releaseTimeout time.Duration
+	keyFunc        KeyFunc
+	keyGenerator   func() string
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithTTL sets how long the response of a call is kept for its idempotency key.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithInProgressTTL sets how long an idempotency key is reserved while its call is being handled. It bounds
+// the time a key stays unusable if the server dies before the call finishes.
+func WithInProgressTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.inProgressTTL = ttl
+	}
+}
+
+// WithReleaseTimeout sets how long releasing the idempotency key of a call that isn't stored may take. Keys are
+// released outside of the context of their call, which is usually done by then.
+func WithReleaseTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.releaseTimeout = timeout
+	}
+}
+
+// KeyFunc returns the key of the store under which the response of a call is kept, from the idempotency key sent
+// by the client.
+type KeyFunc func(ctx context.Context, fullMethod string, idempotencyKey string) string
+
+// MethodKey scopes idempotency keys to the method they are used with, which is the default.
+//
+// It doesn't tell callers apart: a caller sending the idempotency key and the request of another caller gets the
+// response stored for them. Use WithKeyFunc to scope keys by caller when responses must not be shared.
+func MethodKey(ctx context.Context, fullMethod string, idempotencyKey string) string {
+	return fullMethod + "/" + idempotencyKey
+}
+
+// WithKeyFunc sets the function giving the key of the store of a call, e.g. to scope idempotency keys by the
+// identity of the caller authenticated by grpc_auth.
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithKeyGenerator sets the function generating the keys added by the client interceptor.
+func WithKeyGenerator(f func() string) Option {
+	return func(o *options) {
+		o.keyGenerator = f
+	}
+}
+
+// RandomKey generates a random 128-bit idempotency key.
+func RandomKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+// Entry is what a Store keeps for an idempotency key.
+type Entry struct {
+	// InProgress is true while the first call with the key is being handled.
+	InProgress bool
+	// Response is the response of the call once it succeeded.
+	Response *any.Any
+	// RequestHash identifies the request of the call, so that a key reused for a different request is rejected.
+	RequestHash []byte
+}
+
+// Store keeps the entries of idempotency keys, e.g. in memory or in a database shared by all the
+// instances of a service.
+type Store interface {
+	// Reserve atomically marks the key as in progress for ttl, with the hash of the request of the call,
+	// unless the key already has an entry, in which case that entry is returned and nothing is changed.
+	Reserve(ctx context.Context, key string, requestHash []byte, ttl time.Duration) (*Entry, error)
+	// Save stores the response of the call made with the key, and the hash of its request, for ttl.
+	Save(ctx context.Context, key string, requestHash []byte, response *any.Any, ttl time.Duration) error
+	// Release removes the entry of the key, so that the call can be made again.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryStore is a Store keeping the entries in memory. It is only suitable for services running a single instance.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	Entry
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key string, requestHash []byte, ttl time.Duration) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweepLocked(now)
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		entry := e.Entry
+		return &entry, nil
+	}
+	s.entries[key] = memoryEntry{Entry: Entry{InProgress: true, RequestHash: requestHash}, expiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, key string, requestHash []byte, response *any.Any, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{Entry: Entry{Response: response, RequestHash: requestHash}, expiresAt: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// sweepLocked removes the expired entries, at most once a minute.
+func (s *MemoryStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -4,6 +4,7 @@
 package grpc_retry
 
 import (
+	"context"
 	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/util/backoffutils"
@@ -30,15 +31,82 @@ func BackoffLinearWithJitter(waitBetween time.Duration, jitterFraction float64)
 // The scalar is multiplied times 2 raised to the current attempt. So the first
 // retry with a scalar of 100ms is 100ms, while the 5th attempt would be 1.6s.
 func BackoffExponential(scalar time.Duration) BackoffFunc {
-	return func(attempt uint) time.Duration {
-		return scalar * time.Duration(backoffutils.ExponentBase2(attempt))
-	}
+	return BackoffExponentialCapped(scalar, 0)
 }
 
 // BackoffExponentialWithJitter creates an exponential backoff like
 // BackoffExponential does, but adds jitter.
 func BackoffExponentialWithJitter(scalar time.Duration, jitterFraction float64) BackoffFunc {
 	return func(attempt uint) time.Duration {
-		return backoffutils.JitterUp(scalar*time.Duration(backoffutils.ExponentBase2(attempt)), jitterFraction)
+		return backoffutils.JitterUp(backoffutils.ExponentialCapped(scalar, 0, attempt), jitterFraction)
+	}
+}
+
+// BackoffExponentialCapped creates an exponential backoff like BackoffExponential does, but never waits
+// longer than maxWait. A maxWait of 0 disables the cap.
+func BackoffExponentialCapped(scalar time.Duration, maxWait time.Duration) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		return backoffutils.ExponentialCapped(scalar, maxWait, attempt)
+	}
+}
+
+// BackoffFullJitter waits a random time between 0 and the capped exponential backoff of the attempt.
+//
+// It spreads retries the most, and is the recommended strategy to avoid retry storms.
+func BackoffFullJitter(scalar time.Duration, maxWait time.Duration) BackoffFunc {
+	return BackoffFullJitterWithRand(scalar, maxWait, nil)
+}
+
+// BackoffFullJitterWithRand is BackoffFullJitter drawing its random numbers from rnd.
+func BackoffFullJitterWithRand(scalar time.Duration, maxWait time.Duration, rnd backoffutils.RandFunc) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		return backoffutils.FullJitter(scalar, maxWait, attempt, rnd)
 	}
 }
+
+// BackoffEqualJitter waits a random time between half of the capped exponential backoff of the attempt
+// and all of it.
+func BackoffEqualJitter(scalar time.Duration, maxWait time.Duration) BackoffFunc {
+	return BackoffEqualJitterWithRand(scalar, maxWait, nil)
+}
+
+// BackoffEqualJitterWithRand is BackoffEqualJitter drawing its random numbers from rnd.
+func BackoffEqualJitterWithRand(scalar time.Duration, maxWait time.Duration, rnd backoffutils.RandFunc) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		return backoffutils.EqualJitter(scalar, maxWait, attempt, rnd)
+	}
+}
+
+// BackoffDecorrelatedJitter waits a random time between base and three times the previous wait of the call,
+// never longer than maxWait.
+//
+// As it depends on the waits of the call, it is a `BackoffFuncContext`, set with `WithBackoffContext`.
+func BackoffDecorrelatedJitter(base time.Duration, maxWait time.Duration) BackoffFuncContext {
+	return BackoffDecorrelatedJitterWithRand(base, maxWait, nil)
+}
+
+// BackoffDecorrelatedJitterWithRand is BackoffDecorrelatedJitter drawing its random numbers from rnd.
+func BackoffDecorrelatedJitterWithRand(base time.Duration, maxWait time.Duration, rnd backoffutils.RandFunc) BackoffFuncContext {
+	return func(ctx context.Context, attempt uint) time.Duration {
+		previous := previousWait(ctx)
+		if previous <= 0 {
+			previous = base
+		}
+		return backoffutils.DecorrelatedJitter(base, maxWait, previous, rnd)
+	}
+}
+
+type previousWaitKey struct{}
+
+// withPreviousWait passes the last wait of the call to the BackoffFuncContext computing the next one.
+func withPreviousWait(ctx context.Context, wait time.Duration) context.Context {
+	if wait <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, previousWaitKey{}, wait)
+}
+
+func previousWait(ctx context.Context) time.Duration {
+	wait, _ := ctx.Value(previousWaitKey{}).(time.Duration)
+	return wait
+}
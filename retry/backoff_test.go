@@ -0,0 +1,83 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_retry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func fixedRand(v float64) func() float64 {
+	return func() float64 {
+		return v
+	}
+}
+
+func TestBackoffExponentialCapped(t *testing.T) {
+	backoff := grpc_retry.BackoffExponentialCapped(10*time.Millisecond, 50*time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, backoff(1))
+	assert.Equal(t, 40*time.Millisecond, backoff(3))
+	assert.Equal(t, 50*time.Millisecond, backoff(4), "the backoff must be capped")
+	assert.Equal(t, 50*time.Millisecond, backoff(1000), "large attempts must not overflow")
+	assert.Equal(t, 80*time.Millisecond, grpc_retry.BackoffExponentialCapped(10*time.Millisecond, 0)(4), "a zero cap must disable it")
+}
+
+func TestBackoffFullJitter(t *testing.T) {
+	assert.Equal(t, 20*time.Millisecond, grpc_retry.BackoffFullJitterWithRand(10*time.Millisecond, time.Second, fixedRand(0.5))(3))
+	assert.Equal(t, time.Duration(0), grpc_retry.BackoffFullJitterWithRand(10*time.Millisecond, time.Second, fixedRand(0))(3))
+	assert.Equal(t, 25*time.Millisecond, grpc_retry.BackoffFullJitterWithRand(10*time.Millisecond, 50*time.Millisecond, fixedRand(0.5))(10))
+	for i := 0; i < 100; i++ {
+		wait := grpc_retry.BackoffFullJitter(10*time.Millisecond, time.Second)(3)
+		require.True(t, wait >= 0 && wait < 40*time.Millisecond, "the wait must be within [0, 40ms), got %v", wait)
+	}
+}
+
+func TestBackoffEqualJitter(t *testing.T) {
+	assert.Equal(t, 20*time.Millisecond, grpc_retry.BackoffEqualJitterWithRand(10*time.Millisecond, time.Second, fixedRand(0))(3))
+	assert.Equal(t, 30*time.Millisecond, grpc_retry.BackoffEqualJitterWithRand(10*time.Millisecond, time.Second, fixedRand(0.5))(3))
+	for i := 0; i < 100; i++ {
+		wait := grpc_retry.BackoffEqualJitter(10*time.Millisecond, time.Second)(3)
+		require.True(t, wait >= 20*time.Millisecond && wait < 40*time.Millisecond, "the wait must be within [20ms, 40ms), got %v", wait)
+	}
+}
+
+func TestBackoffDecorrelatedJitter_FollowsThePreviousWaits(t *testing.T) {
+	observer := &recordingObserver{}
+	interceptor := grpc_retry.UnaryClientInterceptor(
+		grpc_retry.WithMax(4),
+		grpc_retry.WithCodes(codes.Unavailable),
+		grpc_retry.WithBackoffContext(grpc_retry.BackoffDecorrelatedJitterWithRand(time.Millisecond, 5*time.Millisecond, fixedRand(0.5))),
+		grpc_retry.WithObservers(observer.observe),
+	)
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+	for i := 0; i < 2; i++ {
+		err := interceptor(context.Background(), "/mwitkow.testproto.TestService/Ping", nil, nil, nil, invoker)
+		require.Equal(t, codes.Unavailable, status.Code(err), "all attempts must fail")
+	}
+
+	var waits []time.Duration
+	for _, event := range observer.recorded {
+		if event.Type == grpc_retry.BackoffStarted {
+			waits = append(waits, event.Wait)
+		}
+	}
+	// Each wait is drawn between 1ms and three times the previous one, which starts at 1ms for every call.
+	call := []time.Duration{2 * time.Millisecond, 3500 * time.Microsecond, 5 * time.Millisecond}
+	assert.Equal(t, append(call, call...), waits)
+}
+
+func TestBackoffDecorrelatedJitter_OutsideOfCalls(t *testing.T) {
+	backoff := grpc_retry.BackoffDecorrelatedJitterWithRand(10*time.Millisecond, time.Second, fixedRand(0.5))
+	assert.Equal(t, 20*time.Millisecond, backoff(context.Background(), 3), "the previous wait must default to the base")
+}
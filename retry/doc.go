@@ -7,7 +7,12 @@
 Client-Side Request Retry Interceptor
 
 It allows for automatic retry, inside the generated gRPC code of requests based on the gRPC status
-of the reply. It supports unary (1:1), and server stream (1:n) requests.
+of the reply. It supports unary (1:1), server stream (1:n), client stream (n:1) and bidi stream (n:m)
+requests. Client and bidi streams are only retried until the first response is received, as the messages
+sent by the client are buffered and replayed on the new stream; `WithReplayBuffer` bounds that buffer.
+Server streams that break after some responses were received start over, unless a `ResumeFunc` set
+with `WithStreamResume` rewrites the request so that the new stream continues where the previous one
+stopped.
 
 By default the interceptors *are disabled*, preventing accidental use of retries. You can easily
 override the number of retries (setting them to more than 0) with a `grpc.ClientOption`, e.g.:
@@ -17,6 +22,23 @@ override the number of retries (setting them to more than 0) with a `grpc.Client
 Other default options are: retry on `ResourceExhausted` and `Unavailable` gRPC codes, use a 50ms
 linear backoff with 10% jitter.
 
+With `WithServerPushback(true)`, the interceptors honour the retry delay requested by the server, either
+through a `google.rpc.RetryInfo` status detail or a `grpc-retry-pushback-ms` trailer, up to the limit set with
+`WithMaxServerPushback`, and stop retrying if the server sends a negative pushback.
+
+To avoid retry storms when a backend is down, a `Throttler` can be shared by the interceptors of a
+`grpc.ClientConn` through `WithThrottler`. It follows the gRPC retry throttling design and stops retrying
+calls to a target once too many of them fail.
+
+Retry policies can also be set per method with a `PolicyTable`, which can be loaded from the `retryPolicy`
+entries of a gRPC service config with `ParseServiceConfig` and passed to the interceptors with
+`WithPolicyTable`.
+
+The progress of each call (attempts, failures, backoffs and the final outcome) can be followed with
+`Observer`s set through `WithObservers`, e.g. `SpanObserver` annotates the OpenTracing span of the caller, and
+`TagsObserver` the `grpc_ctxtags` of the call set by the grpc_ctxtags client interceptor, which has to be chained
+before the retry interceptor. `WithAttemptCount` reports the number of attempts of a call.
+
 For chained interceptors, the retry interceptor will call every interceptor that follows it
 whenever when a retry happens.
 
@@ -7,12 +7,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/retry"
 	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var cc *grpc.ClientConn
@@ -42,6 +44,25 @@ func Example_initializationWithOptions() {
 	)
 }
 
+// Example that only retries `Aborted` calls caused by a lock conflict, as well as the default
+// retriable codes.
+func ExampleWithRetriableFunc() {
+	defaultRetriable := grpc_retry.RetriableCodes(grpc_retry.DefaultRetriableCodes...)
+	opts := []grpc_retry.CallOption{
+		grpc_retry.WithRetriableFunc(func(ctx context.Context, attempt uint, err error) bool {
+			st := status.Convert(err)
+			if st.Code() == codes.Aborted {
+				return strings.Contains(st.Message(), "lock conflict")
+			}
+			return defaultRetriable(ctx, attempt, err)
+		}),
+	}
+	grpc.Dial("myservice.example.com",
+		grpc.WithStreamInterceptor(grpc_retry.StreamClientInterceptor(opts...)),
+		grpc.WithUnaryInterceptor(grpc_retry.UnaryClientInterceptor(opts...)),
+	)
+}
+
 // Example with an exponential backoff starting with 100ms.
 //
 // Each next interval is the previous interval multiplied by 2.
@@ -0,0 +1,202 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_retry
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EventType is the kind of progress reported to an `Observer`.
+type EventType int
+
+const (
+	// AttemptStarted is reported before every attempt of a call, including the first one.
+	AttemptStarted EventType = iota
+	// AttemptFailed is reported when an attempt fails, whether it will be retried or not.
+	AttemptFailed
+	// BackoffStarted is reported before waiting for the next attempt.
+	BackoffStarted
+	// CallFinished is reported once, with the final outcome of the call.
+	CallFinished
+)
+
+func (t EventType) String() string {
+	switch t {
+	case AttemptStarted:
+		return "grpc_retry.attempt_started"
+	case AttemptFailed:
+		return "grpc_retry.attempt_failed"
+	case BackoffStarted:
+		return "grpc_retry.backoff_started"
+	case CallFinished:
+		return "grpc_retry.call_finished"
+	default:
+		return "grpc_retry.unknown"
+	}
+}
+
+// Event describes the progress of a retried call.
+type Event struct {
+	Type   EventType
+	Method string
+	// Attempt is the attempt the event relates to, starting at 0. For BackoffStarted it is the attempt
+	// that will be made after the wait, and for CallFinished the last attempt made.
+	Attempt uint
+	// Code and Err are the outcome of the attempt for AttemptFailed, and of the call for CallFinished.
+	Code codes.Code
+	Err  error
+	// Wait is the backoff duration for BackoffStarted.
+	Wait time.Duration
+}
+
+// Observer is notified of the progress of retried calls, e.g. to record metrics.
+//
+// Observers are called synchronously from the interceptors, and should return quickly.
+type Observer func(ctx context.Context, event Event)
+
+// SpanObserver logs the events of the call to the OpenTracing span of the context, and tags it with the
+// number of attempts made once the call is finished.
+//
+// The span is the one of the caller, so the tracing interceptor has to be chained before the retry interceptor.
+func SpanObserver(ctx context.Context, event Event) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	fields := []log.Field{log.String("event", event.Type.String()), log.Uint32("grpc.retry.attempt", uint32(event.Attempt))}
+	switch event.Type {
+	case AttemptFailed:
+		fields = append(fields, log.String("grpc.code", event.Code.String()), log.Error(event.Err))
+	case BackoffStarted:
+		fields = append(fields, log.String("grpc.retry.wait", event.Wait.String()))
+	case CallFinished:
+		span.SetTag("grpc.retry.attempts", event.Attempt+1)
+	}
+	span.LogFields(fields...)
+}
+
+// TagsObserver records the number of attempts made and the code of the last failed attempt in the
+// `grpc_ctxtags.Tags` of the call.
+//
+// The tags are the ones of the outbound call, so the grpc_ctxtags client interceptor has to be chained before the
+// retry interceptor. Without it, the tags of the context are the ones of the caller, e.g. the request tags of a
+// server handler, which are left untouched.
+func TagsObserver(ctx context.Context, event Event) {
+	tags := grpc_ctxtags.Extract(ctx)
+	if method, _ := grpc_ctxtags.GetString(tags, "grpc.full_method"); method != event.Method {
+		return
+	}
+	switch event.Type {
+	case AttemptStarted:
+		tags.Set("grpc.retry.attempts", event.Attempt+1)
+	case AttemptFailed:
+		tags.Set("grpc.retry.last_code", event.Code.String())
+	}
+}
+
+// callObserver reports the progress of a single call to the observers and the attempt counter of its options.
+//
+// The attempts of a stream are made from the goroutine receiving its messages, while it is created from
+// another one, so the state of the call is guarded by mu.
+type callObserver struct {
+	opts     *options
+	method   string
+	mu       sync.Mutex
+	attempts uint
+	lastWait time.Duration // the last backoff of the call, for the BackoffFuncContext computing the next one
+	finished bool
+}
+
+func (c *callObserver) notify(ctx context.Context, event Event) {
+	event.Method = c.method
+	for _, o := range c.opts.observers {
+		o(ctx, event)
+	}
+}
+
+func (c *callObserver) lastAttempt() uint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts - 1
+}
+
+func (c *callObserver) attemptStarted(ctx context.Context) {
+	c.mu.Lock()
+	c.attempts++
+	attempts := c.attempts
+	if c.opts.attemptCount != nil {
+		*c.opts.attemptCount = attempts
+	}
+	c.mu.Unlock()
+	c.notify(ctx, Event{Type: AttemptStarted, Attempt: attempts - 1})
+}
+
+func (c *callObserver) attemptFailed(ctx context.Context, err error) {
+	c.notify(ctx, Event{Type: AttemptFailed, Attempt: c.lastAttempt(), Code: status.Code(err), Err: err})
+}
+
+func (c *callObserver) previousWait() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastWait
+}
+
+func (c *callObserver) backoffStarted(ctx context.Context, wait time.Duration) {
+	c.mu.Lock()
+	c.lastWait = wait
+	c.mu.Unlock()
+	c.notify(ctx, Event{Type: BackoffStarted, Attempt: c.lastAttempt() + 1, Wait: wait})
+}
+
+func (c *callObserver) callFinished(ctx context.Context, err error) {
+	c.mu.Lock()
+	if c.finished {
+		c.mu.Unlock()
+		return
+	}
+	c.finished = true
+	attempt := c.attempts - 1
+	c.mu.Unlock()
+	c.notify(ctx, Event{Type: CallFinished, Attempt: attempt, Code: status.Code(err), Err: err})
+}
+
+// messageReceived reports the end of a stream, which happens when RecvMsg() fails or returns io.EOF, or
+// when the single response of a call without server streaming is received.
+func (c *callObserver) messageReceived(ctx context.Context, err error, serverStreams bool) {
+	switch {
+	case err == io.EOF:
+		c.callFinished(ctx, nil)
+	case err != nil:
+		c.callFinished(ctx, err)
+	case !serverStreams:
+		c.callFinished(ctx, nil)
+	}
+}
+
+// observedStream reports the end of a stream that isn't retried to its callObserver.
+type observedStream struct {
+	grpc.ClientStream
+	ctx           context.Context
+	observer      *callObserver
+	serverStreams bool
+}
+
+func (s *observedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && err != io.EOF {
+		s.observer.attemptFailed(s.ctx, err)
+	}
+	s.observer.messageReceived(s.ctx, err, s.serverStreams)
+	return err
+}
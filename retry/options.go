@@ -9,6 +9,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -18,17 +19,58 @@ var (
 	// `Unavailable` means that system is currently unavailable and the client should retry again.
 	DefaultRetriableCodes = []codes.Code{codes.ResourceExhausted, codes.Unavailable}
 
+	// DefaultMaxServerPushback is the longest retry delay a server can request, unless set with `WithMaxServerPushback`.
+	DefaultMaxServerPushback = 10 * time.Second
+
+	// DefaultReplayBufferMessages and DefaultReplayBufferBytes are the limits of the replay buffer of streams,
+	// unless set with `WithReplayBuffer`.
+	DefaultReplayBufferMessages uint = 1000
+	DefaultReplayBufferBytes    uint = 1 << 20
+
 	defaultOptions = &options{
 		max:            0, // disabled
 		perCallTimeout: 0, // disabled
 		includeHeader:  true,
-		codes:          DefaultRetriableCodes,
+		honourPushback: false,
+		maxPushback:    DefaultMaxServerPushback,
+		retriableFunc:  RetriableCodes(DefaultRetriableCodes...),
 		backoffFunc: BackoffFuncContext(func(ctx context.Context, attempt uint) time.Duration {
 			return BackoffLinearWithJitter(50*time.Millisecond /*jitter*/, 0.10)(attempt)
 		}),
+
+		maxReplayMessages: DefaultReplayBufferMessages,
+		maxReplayBytes:    DefaultReplayBufferBytes,
 	}
 )
 
+// RetriableFunc denotes a family of functions that decide whether a failed call should be retried.
+//
+// They are called with the context of the call, the attempt that failed (starting at 0) and its error.
+// Context errors (`Canceled` and `DeadlineExceeded`) are never passed to them, as they are handled
+// by the interceptors themselves (see `WithPerRetryTimeout`).
+type RetriableFunc func(ctx context.Context, attempt uint, err error) bool
+
+// RetriableCodes returns a `RetriableFunc` that retries calls that failed with any of the given codes.
+func RetriableCodes(retryCodes ...codes.Code) RetriableFunc {
+	return func(ctx context.Context, attempt uint, err error) bool {
+		errCode := status.Code(err)
+		for _, code := range retryCodes {
+			if code == errCode {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ResumeFunc denotes a family of functions that allow a retried server stream to continue where the
+// previous stream broke, instead of starting over.
+//
+// They are called with the original request of the stream and the last response successfully received
+// by the client, and should return the request to send on the new stream, e.g. a copy of the original
+// request with a resume token or offset set. Returning an error fails the stream with that error.
+type ResumeFunc func(ctx context.Context, req interface{}, lastReceived interface{}) (interface{}, error)
+
 // BackoffFunc denotes a family of functions that control the backoff duration between call retries.
 //
 // They are called with an identifier of the attempt, and should return a time the system client should
@@ -80,9 +122,21 @@ func WithBackoffContext(bf BackoffFuncContext) CallOption {
 // Please *use with care*, as you may be retrying non-idempotent calls.
 //
 // You cannot automatically retry on Cancelled and Deadline, please use `WithPerRetryTimeout` for these.
+//
+// It is a shorthand for `WithRetriableFunc(RetriableCodes(retryCodes...))`, and replaces any `RetriableFunc`
+// set before.
 func WithCodes(retryCodes ...codes.Code) CallOption {
+	return WithRetriableFunc(RetriableCodes(retryCodes...))
+}
+
+// WithRetriableFunc sets the `RetriableFunc` that decides which failed calls should be retried.
+//
+// It allows for decisions beyond the status code, e.g. based on the status message or details, the
+// number of attempts already made or the idempotency of the method. Please *use with care*, as you
+// may be retrying non-idempotent calls.
+func WithRetriableFunc(f RetriableFunc) CallOption {
 	return CallOption{applyFunc: func(o *options) {
-		o.codes = retryCodes
+		o.retriableFunc = f
 	}}
 }
 
@@ -104,12 +158,117 @@ func WithPerRetryTimeout(timeout time.Duration) CallOption {
 	}}
 }
 
+// WithThrottler sets the `Throttler` that limits retries when a large proportion of calls to a target fail.
+//
+// Pass the same Throttler to all interceptors of a `grpc.ClientConn` so that they share a single budget.
+// A nil Throttler disables throttling, which is the default.
+func WithThrottler(t *Throttler) CallOption {
+	return CallOption{applyFunc: func(o *options) {
+		o.throttler = t
+	}}
+}
+
+// WithServerPushback sets whether the retry delay requested by the server is honoured.
+//
+// When enabled, a `google.rpc.RetryInfo` status detail or a `grpc-retry-pushback-ms` trailer returned with
+// a failed attempt replaces the `BackoffFunc` wait before the next retry, and a negative
+// `grpc-retry-pushback-ms` value stops the retries altogether. It is disabled by default, as it requests
+// the trailer of every unary call.
+//
+// The delay requested by the server is capped by `WithMaxServerPushback`.
+func WithServerPushback(enabled bool) CallOption {
+	return CallOption{applyFunc: func(o *options) {
+		o.honourPushback = enabled
+	}}
+}
+
+// WithMaxServerPushback sets the longest retry delay the server can request, `DefaultMaxServerPushback` by
+// default. Longer delays are shortened to maxDelay.
+//
+// With a `RetryPolicy`, the delay is capped by its MaxBackoff instead.
+func WithMaxServerPushback(maxDelay time.Duration) CallOption {
+	return CallOption{applyFunc: func(o *options) {
+		o.maxPushback = maxDelay
+	}}
+}
+
+// WithReplayBuffer limits the messages buffered by the stream interceptor to replay them on retries.
+//
+// Once more than maxMessages messages or maxBytes bytes (of serialized protobuf messages) have been sent
+// on a stream, the buffer is released and the stream is no longer retried. A value of 0 disables the
+// corresponding limit. By default, the buffer holds up to `DefaultReplayBufferMessages` messages and
+// `DefaultReplayBufferBytes` bytes.
+func WithReplayBuffer(maxMessages uint, maxBytes uint) CallOption {
+	return CallOption{applyFunc: func(o *options) {
+		o.maxReplayMessages = maxMessages
+		o.maxReplayBytes = maxBytes
+	}}
+}
+
+// WithStreamResume sets the `ResumeFunc` used to rewrite the request of a server stream when it is retried
+// after receiving some of the responses.
+//
+// By default, retried server streams resend the original request, and the responses received before
+// the failure will be received again. The ResumeFunc keeps a reference to the last received message, so
+// it must not be modified by the caller of RecvMsg().
+func WithStreamResume(f ResumeFunc) CallOption {
+	return CallOption{applyFunc: func(o *options) {
+		o.resumeFunc = f
+	}}
+}
+
+// WithPolicyTable sets the `PolicyTable` consulted for the retry policy of each method.
+//
+// The policy of a method overrides the options of the interceptor, while the options passed on the call
+// override the policy.
+func WithPolicyTable(t *PolicyTable) CallOption {
+	return CallOption{applyFunc: func(o *options) {
+		o.policies = t
+	}}
+}
+
+// WithObservers sets the `Observer`s notified of the progress of each call, replacing any set before.
+func WithObservers(observers ...Observer) CallOption {
+	return CallOption{applyFunc: func(o *options) {
+		o.observers = observers
+	}}
+}
+
+// WithAttemptCount makes the interceptor store the number of attempts made by the call in count.
+//
+// It is meant to be passed on the call, e.g.:
+//
+//	var attempts uint
+//	myclient.Ping(ctx, goodPing, grpc_retry.WithAttemptCount(&attempts))
+//
+// For streams, the count is updated whenever the stream is retried.
+func WithAttemptCount(count *uint) CallOption {
+	return CallOption{applyFunc: func(o *options) {
+		o.attemptCount = count
+	}}
+}
+
 type options struct {
 	max            uint
 	perCallTimeout time.Duration
 	includeHeader  bool
-	codes          []codes.Code
+	honourPushback bool
+	maxPushback    time.Duration
+	retriableFunc  RetriableFunc
 	backoffFunc    BackoffFuncContext
+	throttler      *Throttler
+	resumeFunc     ResumeFunc
+	policies       *PolicyTable
+	observers      []Observer
+	attemptCount   *uint
+
+	maxReplayMessages uint
+	maxReplayBytes    uint
+}
+
+func (o *options) exceedsReplayBuffer(messages int, bytes int) bool {
+	return (o.maxReplayMessages > 0 && uint(messages) > o.maxReplayMessages) ||
+		(o.maxReplayBytes > 0 && uint(bytes) > o.maxReplayBytes)
 }
 
 // CallOption is a grpc.CallOption that is local to grpc_retry.
@@ -130,6 +289,18 @@ func reuseOrNewWithCallOptions(opt *options, callOptions []CallOption) *options
 	return optCopy
 }
 
+// callOptionsFor returns the options of a call to the given method: the options of the interceptor,
+// overridden by the policy of the method, overridden by the options of the call.
+func callOptionsFor(intOpts *options, method string, retryOptions []CallOption) *options {
+	callOpts := reuseOrNewWithCallOptions(intOpts, retryOptions)
+	policy := callOpts.policies.lookup(method)
+	if policy == nil {
+		return callOpts
+	}
+	withPolicy := append([]CallOption{{applyFunc: policy.applyTo}}, retryOptions...)
+	return reuseOrNewWithCallOptions(intOpts, withPolicy)
+}
+
 func filterCallOptions(callOptions []grpc.CallOption) (grpcOptions []grpc.CallOption, retryOptions []CallOption) {
 	for _, opt := range callOptions {
 		if co, ok := opt.(CallOption); ok {
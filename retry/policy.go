@@ -0,0 +1,220 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_retry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// maxPolicyAttempts is the limit of the maxAttempts of a retry policy set by the gRPC service config specification.
+const maxPolicyAttempts = 5
+
+// RetryPolicy is the retry configuration of a method, mirroring the `retryPolicy` of the gRPC service config
+// (https://github.com/grpc/grpc-proto/blob/master/grpc/service_config/service_config.proto).
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the original call. As in the gRPC service config,
+	// values greater than 5 are treated as 5.
+	MaxAttempts uint
+	// InitialBackoff, MaxBackoff and BackoffMultiplier control the wait before each retry, which is a random
+	// duration between 0 and min(InitialBackoff * BackoffMultiplier^(n-1), MaxBackoff) for the n-th retry.
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// RetryableStatusCodes are the codes for which the call is retried.
+	RetryableStatusCodes []codes.Code
+}
+
+func (p *RetryPolicy) validate() error {
+	if p.MaxAttempts < 2 {
+		return fmt.Errorf("maxAttempts must be greater than 1, got %d", p.MaxAttempts)
+	}
+	if p.InitialBackoff <= 0 {
+		return fmt.Errorf("initialBackoff must be greater than 0, got %v", p.InitialBackoff)
+	}
+	if p.MaxBackoff <= 0 {
+		return fmt.Errorf("maxBackoff must be greater than 0, got %v", p.MaxBackoff)
+	}
+	if p.BackoffMultiplier <= 0 {
+		return fmt.Errorf("backoffMultiplier must be greater than 0, got %v", p.BackoffMultiplier)
+	}
+	if len(p.RetryableStatusCodes) == 0 {
+		return fmt.Errorf("retryableStatusCodes must not be empty")
+	}
+	return nil
+}
+
+func (p *RetryPolicy) backoff(attempt uint) time.Duration {
+	maxWait := float64(p.InitialBackoff)
+	for i := uint(1); i < attempt && maxWait < float64(p.MaxBackoff); i++ {
+		maxWait *= p.BackoffMultiplier
+	}
+	if maxWait > float64(p.MaxBackoff) {
+		maxWait = float64(p.MaxBackoff)
+	}
+	if maxWait < 1 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(maxWait)))
+}
+
+func (p *RetryPolicy) applyTo(o *options) {
+	o.max = p.MaxAttempts
+	o.retriableFunc = RetriableCodes(p.RetryableStatusCodes...)
+	o.maxPushback = p.MaxBackoff
+	o.backoffFunc = func(ctx context.Context, attempt uint) time.Duration {
+		return p.backoff(attempt)
+	}
+}
+
+// PolicyTable holds the `RetryPolicy` of gRPC methods, keyed by service and method name.
+//
+// A policy set for a method takes precedence over a policy set for its whole service, which takes
+// precedence over the default policy.
+type PolicyTable struct {
+	methods  map[string]*RetryPolicy
+	services map[string]*RetryPolicy
+	fallback *RetryPolicy
+}
+
+// NewPolicyTable creates an empty PolicyTable.
+func NewPolicyTable() *PolicyTable {
+	return &PolicyTable{
+		methods:  make(map[string]*RetryPolicy),
+		services: make(map[string]*RetryPolicy),
+	}
+}
+
+// Set sets the policy of a method, e.g. `Set("mwitkow.testproto.TestService", "Ping", policy)`.
+//
+// An empty method sets the policy of all the methods of the service, and an empty service and method
+// set the default policy. It returns an error if the policy is invalid.
+func (t *PolicyTable) Set(service string, method string, policy RetryPolicy) error {
+	if err := policy.validate(); err != nil {
+		return fmt.Errorf("grpc_retry: invalid retry policy for %q: %v", service+"/"+method, err)
+	}
+	if policy.MaxAttempts > maxPolicyAttempts {
+		policy.MaxAttempts = maxPolicyAttempts
+	}
+	switch {
+	case service == "" && method == "":
+		t.fallback = &policy
+	case service == "":
+		return fmt.Errorf("grpc_retry: method %q set without a service", method)
+	case method == "":
+		t.services[service] = &policy
+	default:
+		t.methods["/"+service+"/"+method] = &policy
+	}
+	return nil
+}
+
+// Lookup returns the policy of a full method name (e.g. `/mwitkow.testproto.TestService/Ping`).
+func (t *PolicyTable) Lookup(fullMethod string) (RetryPolicy, bool) {
+	if p := t.lookup(fullMethod); p != nil {
+		return *p, true
+	}
+	return RetryPolicy{}, false
+}
+
+func (t *PolicyTable) lookup(fullMethod string) *RetryPolicy {
+	if t == nil {
+		return nil
+	}
+	if p, ok := t.methods[fullMethod]; ok {
+		return p
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		if p, ok := t.services[fullMethod[1:i]]; ok {
+			return p
+		}
+	}
+	return t.fallback
+}
+
+type jsonServiceConfig struct {
+	MethodConfig []struct {
+		Name []struct {
+			Service string `json:"service"`
+			Method  string `json:"method"`
+		} `json:"name"`
+		RetryPolicy *struct {
+			MaxAttempts          uint         `json:"maxAttempts"`
+			InitialBackoff       string       `json:"initialBackoff"`
+			MaxBackoff           string       `json:"maxBackoff"`
+			BackoffMultiplier    float64      `json:"backoffMultiplier"`
+			RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+		} `json:"retryPolicy"`
+	} `json:"methodConfig"`
+}
+
+// ParseServiceConfig builds a PolicyTable from the `retryPolicy` entries of a gRPC service config in JSON,
+// so that the same configuration drives the retries of clients in every language. e.g.:
+//
+//	{
+//	  "methodConfig": [{
+//	    "name": [{"service": "mwitkow.testproto.TestService", "method": "Ping"}],
+//	    "retryPolicy": {
+//	      "maxAttempts": 4,
+//	      "initialBackoff": "0.1s",
+//	      "maxBackoff": "1s",
+//	      "backoffMultiplier": 2,
+//	      "retryableStatusCodes": ["UNAVAILABLE"]
+//	    }
+//	  }]
+//	}
+//
+// Method configs without a `retryPolicy`, and any other field of the service config, are ignored.
+func ParseServiceConfig(serviceConfigJSON []byte) (*PolicyTable, error) {
+	var sc jsonServiceConfig
+	if err := json.Unmarshal(serviceConfigJSON, &sc); err != nil {
+		return nil, fmt.Errorf("grpc_retry: failed parsing service config: %v", err)
+	}
+	table := NewPolicyTable()
+	for _, mc := range sc.MethodConfig {
+		if mc.RetryPolicy == nil {
+			continue
+		}
+		initialBackoff, err := parseJSONDuration(mc.RetryPolicy.InitialBackoff)
+		if err != nil {
+			return nil, fmt.Errorf("grpc_retry: invalid initialBackoff: %v", err)
+		}
+		maxBackoff, err := parseJSONDuration(mc.RetryPolicy.MaxBackoff)
+		if err != nil {
+			return nil, fmt.Errorf("grpc_retry: invalid maxBackoff: %v", err)
+		}
+		policy := RetryPolicy{
+			MaxAttempts:          mc.RetryPolicy.MaxAttempts,
+			InitialBackoff:       initialBackoff,
+			MaxBackoff:           maxBackoff,
+			BackoffMultiplier:    mc.RetryPolicy.BackoffMultiplier,
+			RetryableStatusCodes: mc.RetryPolicy.RetryableStatusCodes,
+		}
+		if len(mc.Name) == 0 {
+			if err := table.Set("", "", policy); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range mc.Name {
+			if err := table.Set(name.Service, name.Method, policy); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return table, nil
+}
+
+// parseJSONDuration parses the JSON representation of a `google.protobuf.Duration`, e.g. "1.5s".
+func parseJSONDuration(s string) (time.Duration, error) {
+	if !strings.HasSuffix(s, "s") {
+		return 0, fmt.Errorf("malformed duration %q", s)
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_retry_test
+
+import (
+	"testing"
+	"time"
+
+	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+const testServiceConfig = `{
+  "loadBalancingConfig": [{"round_robin": {}}],
+  "methodConfig": [
+    {
+      "name": [{"service": "mwitkow.testproto.TestService", "method": "Ping"}],
+      "retryPolicy": {
+        "maxAttempts": 4,
+        "initialBackoff": "0.1s",
+        "maxBackoff": "1s",
+        "backoffMultiplier": 2,
+        "retryableStatusCodes": ["UNAVAILABLE", "DATA_LOSS"]
+      }
+    },
+    {
+      "name": [{"service": "mwitkow.testproto.TestService"}],
+      "retryPolicy": {
+        "maxAttempts": 2,
+        "initialBackoff": "0.01s",
+        "maxBackoff": "0.01s",
+        "backoffMultiplier": 1,
+        "retryableStatusCodes": [14]
+      }
+    },
+    {
+      "name": [{"service": "mwitkow.testproto.OtherService"}],
+      "timeout": "1s"
+    }
+  ]
+}`
+
+func TestParseServiceConfig(t *testing.T) {
+	table, err := grpc_retry.ParseServiceConfig([]byte(testServiceConfig))
+	require.NoError(t, err, "parsing a valid service config must not fail")
+
+	policy, ok := table.Lookup("/mwitkow.testproto.TestService/Ping")
+	require.True(t, ok, "the method policy must be found")
+	assert.EqualValues(t, 4, policy.MaxAttempts)
+	assert.Equal(t, 100*time.Millisecond, policy.InitialBackoff)
+	assert.Equal(t, time.Second, policy.MaxBackoff)
+	assert.Equal(t, 2.0, policy.BackoffMultiplier)
+	assert.Equal(t, []codes.Code{codes.Unavailable, codes.DataLoss}, policy.RetryableStatusCodes)
+
+	policy, ok = table.Lookup("/mwitkow.testproto.TestService/PingList")
+	require.True(t, ok, "the service policy must be used for methods without their own policy")
+	assert.EqualValues(t, 2, policy.MaxAttempts)
+	assert.Equal(t, []codes.Code{codes.Unavailable}, policy.RetryableStatusCodes)
+
+	_, ok = table.Lookup("/mwitkow.testproto.OtherService/Ping")
+	assert.False(t, ok, "method configs without retry policies must be ignored")
+}
+
+func TestParseServiceConfig_DefaultPolicy(t *testing.T) {
+	table, err := grpc_retry.ParseServiceConfig([]byte(`{"methodConfig": [{"name": [{}], "retryPolicy": {
+		"maxAttempts": 3, "initialBackoff": "1s", "maxBackoff": "2s", "backoffMultiplier": 1.5,
+		"retryableStatusCodes": ["RESOURCE_EXHAUSTED"]}}]}`))
+	require.NoError(t, err, "parsing a valid service config must not fail")
+	policy, ok := table.Lookup("/any.Service/Method")
+	require.True(t, ok, "the default policy must be used for all methods")
+	assert.EqualValues(t, 3, policy.MaxAttempts)
+}
+
+func TestParseServiceConfig_ClampsMaxAttempts(t *testing.T) {
+	table, err := grpc_retry.ParseServiceConfig([]byte(`{"methodConfig": [{"name": [{}], "retryPolicy": {
+		"maxAttempts": 10, "initialBackoff": "1s", "maxBackoff": "2s", "backoffMultiplier": 1.5,
+		"retryableStatusCodes": ["RESOURCE_EXHAUSTED"]}}]}`))
+	require.NoError(t, err, "parsing a valid service config must not fail")
+	policy, ok := table.Lookup("/any.Service/Method")
+	require.True(t, ok, "the default policy must be used for all methods")
+	assert.EqualValues(t, 5, policy.MaxAttempts, "maxAttempts must be limited to 5")
+}
+
+func TestParseServiceConfig_InvalidPolicies(t *testing.T) {
+	for name, retryPolicy := range map[string]string{
+		"maxAttempts":    `{"maxAttempts": 1, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]}`,
+		"initialBackoff": `{"maxAttempts": 2, "initialBackoff": "1", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]}`,
+		"maxBackoff":     `{"maxAttempts": 2, "initialBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]}`,
+		"multiplier":     `{"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "retryableStatusCodes": ["UNAVAILABLE"]}`,
+		"codes":          `{"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": []}`,
+		"unknownCode":    `{"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["NOT_A_CODE"]}`,
+	} {
+		config := `{"methodConfig": [{"name": [{"service": "s"}], "retryPolicy": ` + retryPolicy + `}]}`
+		_, err := grpc_retry.ParseServiceConfig([]byte(config))
+		assert.Error(t, err, "invalid %s must fail parsing", name)
+	}
+}
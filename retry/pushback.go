@@ -0,0 +1,49 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_retry
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// PushbackMetadataKey is the trailer in which the server can request a delay (in milliseconds) before
+	// the next retry. A negative or malformed value signals that the call should not be retried.
+	PushbackMetadataKey = "grpc-retry-pushback-ms"
+
+	noPushback time.Duration = -1
+)
+
+// serverPushback extracts the retry delay requested by the server from the error of a failed attempt and
+// its trailer. It returns noPushback if the server didn't request anything, and false if the server asked
+// for the call not to be retried.
+//
+// The `grpc-retry-pushback-ms` trailer takes precedence over a `google.rpc.RetryInfo` status detail.
+func serverPushback(err error, trailer metadata.MD) (time.Duration, bool) {
+	if vals := trailer.Get(PushbackMetadataKey); len(vals) > 0 {
+		ms, parseErr := strconv.ParseInt(vals[0], 10, 64)
+		if parseErr != nil || ms < 0 {
+			return noPushback, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	for _, detail := range status.Convert(err).Details() {
+		retryInfo, ok := detail.(*errdetails.RetryInfo)
+		if !ok || retryInfo.GetRetryDelay() == nil {
+			continue
+		}
+		delay, convErr := ptypes.Duration(retryInfo.GetRetryDelay())
+		if convErr != nil || delay < 0 {
+			continue
+		}
+		return delay, true
+	}
+	return noPushback, true
+}
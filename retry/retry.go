@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
 	"golang.org/x/net/trace"
 	"google.golang.org/grpc"
@@ -28,24 +29,56 @@ const (
 // changed through options (e.g. WithMax) on creation of the interceptor or on call (through grpc.CallOptions).
 func UnaryClientInterceptor(optFuncs ...CallOption) grpc.UnaryClientInterceptor {
 	intOpts := reuseOrNewWithCallOptions(defaultOptions, optFuncs)
-	return func(parentCtx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return func(parentCtx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
 		grpcOpts, retryOpts := filterCallOptions(opts)
-		callOpts := reuseOrNewWithCallOptions(intOpts, retryOpts)
+		callOpts := callOptionsFor(intOpts, method, retryOpts)
 		// short circuit for simplicity, and avoiding allocations.
-		if callOpts.max == 0 {
+		if callOpts.max == 0 && len(callOpts.observers) == 0 {
+			if callOpts.attemptCount != nil {
+				*callOpts.attemptCount = 1
+			}
 			return invoker(parentCtx, method, req, reply, cc, grpcOpts...)
 		}
+		observer := &callObserver{opts: callOpts, method: method}
+		if callOpts.max == 0 {
+			observer.attemptStarted(parentCtx)
+			err := invoker(parentCtx, method, req, reply, cc, grpcOpts...)
+			if err != nil {
+				observer.attemptFailed(parentCtx, err)
+			}
+			observer.callFinished(parentCtx, err)
+			return err
+		}
+		defer func() {
+			observer.callFinished(parentCtx, err)
+		}()
+		bucket := callOpts.throttler.bucketFor(cc)
+		var trailer metadata.MD
+		attemptOpts := grpcOpts
+		if callOpts.honourPushback {
+			attemptOpts = append(grpcOpts[:len(grpcOpts):len(grpcOpts)], grpc.Trailer(&trailer))
+		}
 		var lastErr error
+		pushback := noPushback
 		for attempt := uint(0); attempt < callOpts.max; attempt++ {
-			if err := waitRetryBackoff(attempt, parentCtx, callOpts); err != nil {
+			if attempt > 0 && !bucket.allowRetry() {
+				logTrace(parentCtx, "grpc_retry attempt: %d, retry throttled", attempt)
+				return lastErr
+			}
+			if err := waitRetryBackoff(attempt, parentCtx, callOpts, pushback, observer); err != nil {
 				return err
 			}
+			pushback = noPushback
+			trailer = nil
 			callCtx := perCallContext(parentCtx, callOpts, attempt)
-			lastErr = invoker(callCtx, method, req, reply, cc, grpcOpts...)
+			observer.attemptStarted(parentCtx)
+			lastErr = invoker(callCtx, method, req, reply, cc, attemptOpts...)
 			// TODO(mwitkow): Maybe dial and transport errors should be retriable?
 			if lastErr == nil {
+				bucket.recordSuccess()
 				return nil
 			}
+			observer.attemptFailed(parentCtx, lastErr)
 			logTrace(parentCtx, "grpc_retry attempt: %d, got err: %v", attempt, lastErr)
 			if isContextError(lastErr) {
 				if parentCtx.Err() != nil {
@@ -56,12 +89,21 @@ func UnaryClientInterceptor(optFuncs ...CallOption) grpc.UnaryClientInterceptor
 					// We have set a perCallTimeout in the retry middleware, which would result in a context error if
 					// the deadline was exceeded, in which case try again.
 					logTrace(parentCtx, "grpc_retry attempt: %d, context error from retry call", attempt)
+					bucket.recordFailure()
 					continue
 				}
 			}
-			if !isRetriable(lastErr, callOpts) {
+			if !isRetriable(parentCtx, attempt, lastErr, callOpts) {
 				return lastErr
 			}
+			bucket.recordFailure()
+			if callOpts.honourPushback {
+				var retry bool
+				if pushback, retry = serverPushback(lastErr, trailer); !retry {
+					logTrace(parentCtx, "grpc_retry attempt: %d, server pushback prevents retry", attempt)
+					return lastErr
+				}
+			}
 		}
 		return lastErr
 	}
@@ -72,42 +114,72 @@ func UnaryClientInterceptor(optFuncs ...CallOption) grpc.UnaryClientInterceptor
 // The default configuration of the interceptor is to not retry *at all*. This behaviour can be
 // changed through options (e.g. WithMax) on creation of the interceptor or on call (through grpc.CallOptions).
 //
-// Retry logic is available *only for ServerStreams*, i.e. 1:n streams, as the internal logic needs
-// to buffer the messages sent by the client. If retry is enabled on any other streams (ClientStreams,
-// BidiStreams), the retry interceptor will fail the call.
+// The messages sent by the client are buffered, so that they can be replayed on a new stream when the
+// call is retried. ServerStreams, i.e. 1:n streams, are retried whenever a RecvMsg() fails. ClientStreams
+// and BidiStreams are only retried until the first response is received, or until the buffered
+// messages exceed the limits set with `WithReplayBuffer`.
 func StreamClientInterceptor(optFuncs ...CallOption) grpc.StreamClientInterceptor {
 	intOpts := reuseOrNewWithCallOptions(defaultOptions, optFuncs)
-	return func(parentCtx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return func(parentCtx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (_ grpc.ClientStream, err error) {
 		grpcOpts, retryOpts := filterCallOptions(opts)
-		callOpts := reuseOrNewWithCallOptions(intOpts, retryOpts)
+		callOpts := callOptionsFor(intOpts, method, retryOpts)
 		// short circuit for simplicity, and avoiding allocations.
-		if callOpts.max == 0 {
+		if callOpts.max == 0 && len(callOpts.observers) == 0 {
+			if callOpts.attemptCount != nil {
+				*callOpts.attemptCount = 1
+			}
 			return streamer(parentCtx, desc, cc, method, grpcOpts...)
 		}
-		if desc.ClientStreams {
-			return nil, status.Errorf(codes.Unimplemented, "grpc_retry: cannot retry on ClientStreams, set grpc_retry.Disable()")
+		observer := &callObserver{opts: callOpts, method: method}
+		if callOpts.max == 0 {
+			observer.attemptStarted(parentCtx)
+			stream, err := streamer(parentCtx, desc, cc, method, grpcOpts...)
+			if err != nil {
+				observer.attemptFailed(parentCtx, err)
+				observer.callFinished(parentCtx, err)
+				return nil, err
+			}
+			return &observedStream{ClientStream: stream, ctx: parentCtx, observer: observer, serverStreams: desc.ServerStreams}, nil
 		}
-
+		defer func() {
+			if err != nil {
+				observer.callFinished(parentCtx, err)
+			}
+		}()
+		bucket := callOpts.throttler.bucketFor(cc)
 		var lastErr error
+		pushback := noPushback
 		for attempt := uint(0); attempt < callOpts.max; attempt++ {
-			if err := waitRetryBackoff(attempt, parentCtx, callOpts); err != nil {
+			if attempt > 0 && !bucket.allowRetry() {
+				logTrace(parentCtx, "grpc_retry attempt: %d, retry throttled", attempt)
+				return nil, lastErr
+			}
+			if err := waitRetryBackoff(attempt, parentCtx, callOpts, pushback, observer); err != nil {
 				return nil, err
 			}
+			pushback = noPushback
 			callCtx := perCallContext(parentCtx, callOpts, 0)
 
 			var newStreamer grpc.ClientStream
+			observer.attemptStarted(parentCtx)
 			newStreamer, lastErr = streamer(callCtx, desc, cc, method, grpcOpts...)
 			if lastErr == nil {
 				retryingStreamer := &serverStreamingRetryingStream{
-					ClientStream: newStreamer,
-					callOpts:     callOpts,
-					parentCtx:    parentCtx,
+					ClientStream:  newStreamer,
+					callOpts:      callOpts,
+					parentCtx:     parentCtx,
+					bucket:        bucket,
+					observer:      observer,
+					pushback:      noPushback,
+					clientStreams: desc.ClientStreams,
+					serverStreams: desc.ServerStreams,
 					streamerCall: func(ctx context.Context) (grpc.ClientStream, error) {
 						return streamer(ctx, desc, cc, method, grpcOpts...)
 					},
 				}
 				return retryingStreamer, nil
 			}
+			observer.attemptFailed(parentCtx, lastErr)
 
 			logTrace(parentCtx, "grpc_retry attempt: %d, got err: %v", attempt, lastErr)
 			if isContextError(lastErr) {
@@ -119,12 +191,21 @@ func StreamClientInterceptor(optFuncs ...CallOption) grpc.StreamClientIntercepto
 					// We have set a perCallTimeout in the retry middleware, which would result in a context error if
 					// the deadline was exceeded, in which case try again.
 					logTrace(parentCtx, "grpc_retry attempt: %d, context error from retry call", attempt)
+					bucket.recordFailure()
 					continue
 				}
 			}
-			if !isRetriable(lastErr, callOpts) {
+			if !isRetriable(parentCtx, attempt, lastErr, callOpts) {
 				return nil, lastErr
 			}
+			bucket.recordFailure()
+			if callOpts.honourPushback {
+				var retry bool
+				if pushback, retry = serverPushback(lastErr, nil); !retry {
+					logTrace(parentCtx, "grpc_retry attempt: %d, server pushback prevents retry", attempt)
+					return nil, lastErr
+				}
+			}
 		}
 		return nil, lastErr
 	}
@@ -132,15 +213,27 @@ func StreamClientInterceptor(optFuncs ...CallOption) grpc.StreamClientIntercepto
 
 // type serverStreamingRetryingStream is the implementation of grpc.ClientStream that acts as a
 // proxy to the underlying call. If any of the RecvMsg() calls fail, it will try to reestablish
-// a new ClientStream according to the retry policy.
+// a new ClientStream according to the retry policy, and replay the messages sent so far.
+//
+// Client and bidi streams are only retried until the stream is committed, which happens when the
+// first response is received or when the replay buffer limits are exceeded.
 type serverStreamingRetryingStream struct {
 	grpc.ClientStream
-	bufferedSends []interface{} // single message that the client can sen
+	bufferedSends []interface{} // messages sent by the client, replayed on retries
+	bufferedBytes int           // serialized size of bufferedSends
 	wasClosedSend bool          // indicates that CloseSend was closed
+	committed     bool          // indicates that the stream can no longer be retried
+	lastReceived  interface{}   // last message received successfully, used to resume server streams
+	clientStreams bool          // indicates that the client can send more than one message
+	serverStreams bool          // indicates that the server can send more than one message
 	parentCtx     context.Context
 	callOpts      *options
+	bucket        *throttleBucket
+	observer      *callObserver
+	pushback      time.Duration // delay requested by the server before the next retry
 	streamerCall  func(ctx context.Context) (grpc.ClientStream, error)
 	mu            sync.RWMutex
+	sendMu        sync.Mutex // serializes sends with the replay of the buffer on a new stream
 }
 
 func (s *serverStreamingRetryingStream) setStream(clientStream grpc.ClientStream) {
@@ -155,14 +248,46 @@ func (s *serverStreamingRetryingStream) getStream() grpc.ClientStream {
 	return s.ClientStream
 }
 
+func (s *serverStreamingRetryingStream) isCommitted() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.committed
+}
+
+// commitLocked marks the stream as no longer retriable and releases the replay buffer.
+func (s *serverStreamingRetryingStream) commitLocked() {
+	s.committed = true
+	s.bufferedSends = nil
+	s.bufferedBytes = 0
+}
+
 func (s *serverStreamingRetryingStream) SendMsg(m interface{}) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
 	s.mu.Lock()
-	s.bufferedSends = append(s.bufferedSends, m)
+	if !s.committed {
+		s.bufferedSends = append(s.bufferedSends, m)
+		s.bufferedBytes += messageSize(m)
+		if s.callOpts.exceedsReplayBuffer(len(s.bufferedSends), s.bufferedBytes) {
+			logTrace(s.parentCtx, "grpc_retry replay buffer exceeded, stream committed")
+			s.commitLocked()
+		}
+	}
+	committed := s.committed
+	stream := s.ClientStream
 	s.mu.Unlock()
-	return s.getStream().SendMsg(m)
+	err := stream.SendMsg(m)
+	if err == io.EOF && !committed {
+		// The stream has failed and the error will be returned by RecvMsg, which will retry the call
+		// and replay the buffered messages.
+		return nil
+	}
+	return err
 }
 
 func (s *serverStreamingRetryingStream) CloseSend() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
 	s.mu.Lock()
 	s.wasClosedSend = true
 	s.mu.Unlock()
@@ -178,28 +303,49 @@ func (s *serverStreamingRetryingStream) Trailer() metadata.MD {
 }
 
 func (s *serverStreamingRetryingStream) RecvMsg(m interface{}) error {
-	attemptRetry, lastErr := s.receiveMsgAndIndicateRetry(m)
+	err := s.recvMsgWithRetry(m)
+	if err != nil {
+		// The call is over and won't be retried anymore, so SendMsg() must report it with io.EOF instead
+		// of buffering the messages for a retry.
+		s.mu.Lock()
+		s.commitLocked()
+		s.mu.Unlock()
+	}
+	s.observer.messageReceived(s.parentCtx, err, s.serverStreams)
+	return err
+}
+
+func (s *serverStreamingRetryingStream) recvMsgWithRetry(m interface{}) error {
+	attemptRetry, lastErr := s.receiveMsgAndIndicateRetry(m, 0)
 	if !attemptRetry {
 		return lastErr // success or hard failure
 	}
 	// We start off from attempt 1, because zeroth was already made on normal SendMsg().
 	for attempt := uint(1); attempt < s.callOpts.max; attempt++ {
-		if err := waitRetryBackoff(attempt, s.parentCtx, s.callOpts); err != nil {
+		if !s.bucket.allowRetry() {
+			logTrace(s.parentCtx, "grpc_retry attempt: %d, retry throttled", attempt)
+			return lastErr
+		}
+		if err := waitRetryBackoff(attempt, s.parentCtx, s.callOpts, s.pushback, s.observer); err != nil {
 			return err
 		}
+		s.pushback = noPushback
 		callCtx := perCallContext(s.parentCtx, s.callOpts, attempt)
-		newStream, err := s.reestablishStreamAndResendBuffer(callCtx)
-		if err != nil {
+		s.observer.attemptStarted(s.parentCtx)
+		if err := s.reestablishStreamAndResendBuffer(callCtx); err != nil {
+			s.observer.attemptFailed(s.parentCtx, err)
 			// Retry dial and transport errors of establishing stream as grpc doesn't retry.
-			if isRetriable(err, s.callOpts) {
+			if isRetriable(s.parentCtx, attempt, err, s.callOpts) {
+				s.bucket.recordFailure()
+				if !s.acceptPushback(err, nil) {
+					return err
+				}
 				continue
 			}
 			return err
 		}
 
-		s.setStream(newStream)
-		attemptRetry, lastErr = s.receiveMsgAndIndicateRetry(m)
-		//fmt.Printf("Received message and indicate: %v  %v\n", attemptRetry, lastErr)
+		attemptRetry, lastErr = s.receiveMsgAndIndicateRetry(m, attempt)
 		if !attemptRetry {
 			return lastErr
 		}
@@ -207,11 +353,28 @@ func (s *serverStreamingRetryingStream) RecvMsg(m interface{}) error {
 	return lastErr
 }
 
-func (s *serverStreamingRetryingStream) receiveMsgAndIndicateRetry(m interface{}) (bool, error) {
+func (s *serverStreamingRetryingStream) receiveMsgAndIndicateRetry(m interface{}, attempt uint) (bool, error) {
 	err := s.getStream().RecvMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		if s.clientStreams {
+			// The server has responded, so the messages sent from now on can't be replayed safely.
+			s.commitLocked()
+		} else if s.callOpts.resumeFunc != nil {
+			s.lastReceived = m
+		}
+		s.mu.Unlock()
+	}
+	if err == io.EOF || (err == nil && !s.serverStreams) {
+		s.bucket.recordSuccess()
+	}
 	if err == nil || err == io.EOF {
 		return false, err
 	}
+	s.observer.attemptFailed(s.parentCtx, err)
+	if s.isCommitted() {
+		return false, err
+	}
 	if isContextError(err) {
 		if s.parentCtx.Err() != nil {
 			logTrace(s.parentCtx, "grpc_retry parent context error: %v", s.parentCtx.Err())
@@ -220,43 +383,101 @@ func (s *serverStreamingRetryingStream) receiveMsgAndIndicateRetry(m interface{}
 			// We have set a perCallTimeout in the retry middleware, which would result in a context error if
 			// the deadline was exceeded, in which case try again.
 			logTrace(s.parentCtx, "grpc_retry context error from retry call")
+			s.bucket.recordFailure()
 			return true, err
 		}
 	}
-	return isRetriable(err, s.callOpts), err
+	if !isRetriable(s.parentCtx, attempt, err, s.callOpts) {
+		return false, err
+	}
+	s.bucket.recordFailure()
+	return s.acceptPushback(err, s.getStream().Trailer()), err
+}
+
+// acceptPushback records the delay requested by the server for the next retry, and returns false if the
+// server asked for the call not to be retried.
+func (s *serverStreamingRetryingStream) acceptPushback(err error, trailer metadata.MD) bool {
+	if !s.callOpts.honourPushback {
+		return true
+	}
+	pushback, retry := serverPushback(err, trailer)
+	if !retry {
+		logTrace(s.parentCtx, "grpc_retry server pushback prevents retry")
+		return false
+	}
+	s.pushback = pushback
+	return true
 }
 
-func (s *serverStreamingRetryingStream) reestablishStreamAndResendBuffer(
-	callCtx context.Context,
-) (grpc.ClientStream, error) {
+func (s *serverStreamingRetryingStream) reestablishStreamAndResendBuffer(callCtx context.Context) error {
+	// Block SendMsg() until the buffer was replayed on the new stream, so that the order of messages is kept.
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
 	s.mu.RLock()
 	bufferedSends := s.bufferedSends
+	wasClosedSend := s.wasClosedSend
+	lastReceived := s.lastReceived
 	s.mu.RUnlock()
+	if lastReceived != nil {
+		resumed := make([]interface{}, 0, len(bufferedSends))
+		for _, msg := range bufferedSends {
+			req, err := s.callOpts.resumeFunc(callCtx, msg, lastReceived)
+			if err != nil {
+				logTrace(callCtx, "grpc_retry failed resuming stream: %v", err)
+				return err
+			}
+			resumed = append(resumed, req)
+		}
+		bufferedSends = resumed
+	}
 	newStream, err := s.streamerCall(callCtx)
 	if err != nil {
 		logTrace(callCtx, "grpc_retry failed redialing new stream: %v", err)
-		return nil, err
+		return err
 	}
+	s.setStream(newStream)
 	for _, msg := range bufferedSends {
 		if err := newStream.SendMsg(msg); err != nil {
+			if err == io.EOF {
+				// The new stream has failed too, RecvMsg() will return its status.
+				return nil
+			}
 			logTrace(callCtx, "grpc_retry failed resending message: %v", err)
-			return nil, err
+			return err
 		}
 	}
-	if err := newStream.CloseSend(); err != nil {
-		logTrace(callCtx, "grpc_retry failed CloseSend on new stream %v", err)
-		return nil, err
+	if wasClosedSend {
+		if err := newStream.CloseSend(); err != nil {
+			logTrace(callCtx, "grpc_retry failed CloseSend on new stream %v", err)
+			return err
+		}
 	}
-	return newStream, nil
+	return nil
+}
+
+// messageSize returns the serialized size of a message, or 0 if it isn't a protobuf message.
+func messageSize(m interface{}) int {
+	if pm, ok := m.(proto.Message); ok {
+		return proto.Size(pm)
+	}
+	return 0
 }
 
-func waitRetryBackoff(attempt uint, parentCtx context.Context, callOpts *options) error {
+func waitRetryBackoff(attempt uint, parentCtx context.Context, callOpts *options, pushback time.Duration, observer *callObserver) error {
 	var waitTime time.Duration = 0
 	if attempt > 0 {
-		waitTime = callOpts.backoffFunc(parentCtx, attempt)
+		if pushback != noPushback {
+			waitTime = pushback
+			if waitTime > callOpts.maxPushback {
+				waitTime = callOpts.maxPushback
+			}
+		} else {
+			waitTime = callOpts.backoffFunc(withPreviousWait(parentCtx, observer.previousWait()), attempt)
+		}
 	}
 	if waitTime > 0 {
 		logTrace(parentCtx, "grpc_retry attempt: %d, backoff for %v", attempt, waitTime)
+		observer.backoffStarted(parentCtx, waitTime)
 		timer := time.NewTimer(waitTime)
 		select {
 		case <-parentCtx.Done():
@@ -268,18 +489,12 @@ func waitRetryBackoff(attempt uint, parentCtx context.Context, callOpts *options
 	return nil
 }
 
-func isRetriable(err error, callOpts *options) bool {
-	errCode := status.Code(err)
+func isRetriable(ctx context.Context, attempt uint, err error, callOpts *options) bool {
 	if isContextError(err) {
 		// context errors are not retriable based on user settings.
 		return false
 	}
-	for _, code := range callOpts.codes {
-		if code == errCode {
-			return true
-		}
-	}
-	return false
+	return callOpts.retriableFunc != nil && callOpts.retriableFunc(ctx, attempt, err)
 }
 
 func isContextError(err error) bool {
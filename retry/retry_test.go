@@ -5,20 +5,29 @@ package grpc_retry_test
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
 	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -37,6 +46,9 @@ type failingService struct {
 	reqModulo  uint
 	reqSleep   time.Duration
 	reqError   codes.Code
+
+	pushbackTrailer string
+	retryInfoDelay  *time.Duration
 }
 
 func (s *failingService) resetFailingConfiguration(modulo uint, errorCode codes.Code, sleepTime time.Duration) {
@@ -47,6 +59,25 @@ func (s *failingService) resetFailingConfiguration(modulo uint, errorCode codes.
 	s.reqModulo = modulo
 	s.reqError = errorCode
 	s.reqSleep = sleepTime
+	s.pushbackTrailer = ""
+	s.retryInfoDelay = nil
+}
+
+func (s *failingService) setPushback(trailer string, retryInfoDelay *time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pushbackTrailer = trailer
+	s.retryInfoDelay = retryInfoDelay
+}
+
+func (s *failingService) pushbackMetadata() metadata.MD {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pushbackTrailer == "" {
+		return nil
+	}
+	return metadata.Pairs(grpc_retry.PushbackMetadataKey, s.pushbackTrailer)
 }
 
 func (s *failingService) requestCount() uint {
@@ -62,16 +93,24 @@ func (s *failingService) maybeFailRequest() error {
 	reqCounter := s.reqCounter
 	reqSleep := s.reqSleep
 	reqError := s.reqError
+	retryInfoDelay := s.retryInfoDelay
 	s.mu.Unlock()
 	if (reqModulo > 0) && (reqCounter%reqModulo == 0) {
 		return nil
 	}
 	time.Sleep(reqSleep)
-	return status.Errorf(reqError, "maybeFailRequest: failing it")
+	st := status.New(reqError, "maybeFailRequest: failing it")
+	if retryInfoDelay != nil {
+		st, _ = st.WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(*retryInfoDelay)})
+	}
+	return st.Err()
 }
 
 func (s *failingService) Ping(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
 	if err := s.maybeFailRequest(); err != nil {
+		if md := s.pushbackMetadata(); md != nil {
+			grpc.SetTrailer(ctx, md)
+		}
 		return nil, err
 	}
 	return s.TestServiceServer.Ping(ctx, ping)
@@ -79,6 +118,9 @@ func (s *failingService) Ping(ctx context.Context, ping *pb_testproto.PingReques
 
 func (s *failingService) PingList(ping *pb_testproto.PingRequest, stream pb_testproto.TestService_PingListServer) error {
 	if err := s.maybeFailRequest(); err != nil {
+		if md := s.pushbackMetadata(); md != nil {
+			stream.SetTrailer(md)
+		}
 		return err
 	}
 	return s.TestServiceServer.PingList(ping, stream)
@@ -88,7 +130,21 @@ func (s *failingService) PingStream(stream pb_testproto.TestService_PingStreamSe
 	if err := s.maybeFailRequest(); err != nil {
 		return err
 	}
-	return s.TestServiceServer.PingStream(stream)
+	count := 0
+	for {
+		ping, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ping.ErrorCodeReturned != 0 {
+			return status.Errorf(codes.Code(ping.ErrorCodeReturned), "failing mid-stream")
+		}
+		stream.Send(&pb_testproto.PingResponse{Value: ping.Value, Counter: int32(count)})
+		count++
+	}
 }
 
 func TestRetrySuite(t *testing.T) {
@@ -182,6 +238,193 @@ func (s *RetrySuite) TestUnary_OverrideFromDialOpts() {
 	require.EqualValues(s.T(), 5, s.srv.requestCount(), "five requests should have been made")
 }
 
+func (s *RetrySuite) TestUnary_RetriableFuncInspectsStatus() {
+	s.srv.resetFailingConfiguration(3, codes.Aborted, noSleep)
+	retriable := func(ctx context.Context, attempt uint, err error) bool {
+		st := status.Convert(err)
+		return st.Code() == codes.Aborted && strings.Contains(st.Message(), "failing it")
+	}
+	out, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithRetriableFunc(retriable))
+	require.NoError(s.T(), err, "the third invocation should succeed")
+	require.NotNil(s.T(), out, "Pong must be not nil")
+	require.EqualValues(s.T(), 3, s.srv.requestCount(), "three requests should have been made")
+}
+
+func (s *RetrySuite) TestUnary_RetriableFuncReceivesAttempt() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	retriable := func(ctx context.Context, attempt uint, err error) bool {
+		return attempt < 1
+	}
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithRetriableFunc(retriable))
+	require.Error(s.T(), err, "the second invocation must fail and not be retried")
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "failure code must come from the failing service")
+	require.EqualValues(s.T(), 2, s.srv.requestCount(), "two requests should have been made")
+}
+
+func (s *RetrySuite) TestServerStream_RetriableFuncReceivesAttempt() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	retriable := func(ctx context.Context, attempt uint, err error) bool {
+		return attempt < 1
+	}
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing, grpc_retry.WithRetriableFunc(retriable))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	_, err = stream.Recv()
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "failure code must come from the failing service")
+	require.EqualValues(s.T(), 2, s.srv.requestCount(), "two requests should have been made")
+}
+
+func (s *RetrySuite) TestUnary_UsesPolicyTable() {
+	table := grpc_retry.NewPolicyTable()
+	require.NoError(s.T(), table.Set("mwitkow.testproto.TestService", "Ping", grpc_retry.RetryPolicy{
+		MaxAttempts:          5,
+		InitialBackoff:       time.Millisecond,
+		MaxBackoff:           time.Millisecond,
+		BackoffMultiplier:    1,
+		RetryableStatusCodes: []codes.Code{codes.ResourceExhausted},
+	}))
+	s.srv.resetFailingConfiguration(5, codes.ResourceExhausted, noSleep) // not in retriable_errors
+	out, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithPolicyTable(table))
+	require.NoError(s.T(), err, "the fifth invocation should succeed")
+	require.NotNil(s.T(), out, "Pong must be not nil")
+	require.EqualValues(s.T(), 5, s.srv.requestCount(), "five requests should have been made")
+
+	s.srv.resetFailingConfiguration(5, codes.ResourceExhausted, noSleep)
+	_, err = s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithPolicyTable(table), grpc_retry.WithMax(2))
+	require.Error(s.T(), err, "call options must override the policy")
+	require.EqualValues(s.T(), 2, s.srv.requestCount(), "two requests should have been made")
+
+	s.srv.resetFailingConfiguration(5, codes.ResourceExhausted, noSleep)
+	_, err = s.Client.PingEmpty(s.SimpleCtx(), &pb_testproto.Empty{}, grpc_retry.WithPolicyTable(table))
+	require.NoError(s.T(), err, "methods without a policy must use the interceptor options")
+}
+
+func (s *RetrySuite) TestUnary_NotifiesObservers() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep) // see retriable_errors
+	observer := &recordingObserver{}
+	var attempts uint
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithObservers(observer.observe), grpc_retry.WithAttemptCount(&attempts))
+	require.NoError(s.T(), err, "the third invocation should succeed")
+	require.EqualValues(s.T(), 3, attempts, "the attempt count must be reported to the caller")
+	require.Equal(s.T(), []string{
+		"grpc_retry.attempt_started 0 OK",
+		"grpc_retry.attempt_failed 0 DataLoss",
+		"grpc_retry.backoff_started 1 OK",
+		"grpc_retry.attempt_started 1 OK",
+		"grpc_retry.attempt_failed 1 DataLoss",
+		"grpc_retry.backoff_started 2 OK",
+		"grpc_retry.attempt_started 2 OK",
+		"grpc_retry.call_finished 2 OK",
+	}, observer.events())
+	for _, event := range observer.recorded {
+		require.Equal(s.T(), "/mwitkow.testproto.TestService/Ping", event.Method)
+		if event.Type == grpc_retry.BackoffStarted {
+			require.Equal(s.T(), retryTimeout, event.Wait, "the backoff duration must be reported")
+		}
+	}
+}
+
+func (s *RetrySuite) TestUnary_ObserversReportFailure() {
+	s.srv.resetFailingConfiguration(5, codes.Internal, noSleep)
+	observer := &recordingObserver{}
+	var attempts uint
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithObservers(observer.observe), grpc_retry.WithAttemptCount(&attempts))
+	require.Error(s.T(), err, "error must occur from the failing service")
+	require.EqualValues(s.T(), 1, attempts, "the attempt count must be reported to the caller")
+	require.Equal(s.T(), []string{
+		"grpc_retry.attempt_started 0 OK",
+		"grpc_retry.attempt_failed 0 Internal",
+		"grpc_retry.call_finished 0 Internal",
+	}, observer.events())
+}
+
+func (s *RetrySuite) TestServerStream_NotifiesObservers() {
+	s.srv.resetFailingConfiguration(2, codes.DataLoss, noSleep) // see retriable_errors
+	observer := &recordingObserver{}
+	var attempts uint
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing, grpc_retry.WithObservers(observer.observe), grpc_retry.WithAttemptCount(&attempts))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	s.assertPingListWasCorrect(stream)
+	require.EqualValues(s.T(), 2, attempts, "the attempt count must be reported to the caller")
+	require.Equal(s.T(), []string{
+		"grpc_retry.attempt_started 0 OK",
+		"grpc_retry.attempt_failed 0 DataLoss",
+		"grpc_retry.backoff_started 1 OK",
+		"grpc_retry.attempt_started 1 OK",
+		"grpc_retry.call_finished 1 OK",
+	}, observer.events())
+}
+
+func (s *RetrySuite) TestUnary_NotifiesObserversWhenDisabled() {
+	s.srv.resetFailingConfiguration(2, codes.DataLoss, noSleep) // see retriable_errors
+	observer := &recordingObserver{}
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.Disable(), grpc_retry.WithObservers(observer.observe))
+	require.Error(s.T(), err, "the call must not be retried")
+	require.Equal(s.T(), []string{
+		"grpc_retry.attempt_started 0 OK",
+		"grpc_retry.attempt_failed 0 DataLoss",
+		"grpc_retry.call_finished 0 DataLoss",
+	}, observer.events())
+}
+
+func (s *RetrySuite) TestServerStream_NotifiesObserversWhenDisabled() {
+	observer := &recordingObserver{}
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing, grpc_retry.Disable(), grpc_retry.WithObservers(observer.observe))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	s.assertPingListWasCorrect(stream)
+	require.Equal(s.T(), []string{
+		"grpc_retry.attempt_started 0 OK",
+		"grpc_retry.call_finished 0 OK",
+	}, observer.events())
+}
+
+func (s *RetrySuite) TestUnary_SpanAndTagsObservers() {
+	s.srv.resetFailingConfiguration(2, codes.DataLoss, noSleep) // see retriable_errors
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("parent")
+	callerTags := grpc_ctxtags.NewTags().Set("grpc.request.value", "something")
+	ctx := grpc_ctxtags.SetInContext(opentracing.ContextWithSpan(s.SimpleCtx(), span), callerTags)
+	_, err := s.Client.Ping(ctx, goodPing, grpc_retry.WithObservers(grpc_retry.SpanObserver, grpc_retry.TagsObserver))
+	require.NoError(s.T(), err, "the second invocation should succeed")
+	span.Finish()
+
+	finished := tracer.FinishedSpans()
+	require.Len(s.T(), finished, 1, "only the parent span must be recorded")
+	assert.EqualValues(s.T(), 2, finished[0].Tag("grpc.retry.attempts"), "the span must be tagged with the attempts")
+	assert.Len(s.T(), finished[0].Logs(), 5, "every event must be logged to the span")
+	assert.Equal(s.T(), map[string]interface{}{"grpc.request.value": "something"}, callerTags.Values(),
+		"the tags of the caller must not be altered without the grpc_ctxtags client interceptor")
+}
+
+func TestTagsObserver_TagsTheCall(t *testing.T) {
+	callerTags := grpc_ctxtags.NewTags().Set("grpc.request.value", "something")
+	var callTags grpc_ctxtags.Tags
+	interceptor := grpc_middleware.ChainUnaryClient(
+		grpc_ctxtags.UnaryClientInterceptor(),
+		grpc_retry.UnaryClientInterceptor(
+			grpc_retry.WithMax(3),
+			grpc_retry.WithCodes(codes.DataLoss),
+			grpc_retry.WithBackoff(grpc_retry.BackoffLinear(0)),
+			grpc_retry.WithObservers(grpc_retry.TagsObserver),
+		),
+	)
+	attempts := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		callTags = grpc_ctxtags.Extract(ctx)
+		attempts++
+		if attempts == 1 {
+			return status.Error(codes.DataLoss, "failing once")
+		}
+		return nil
+	}
+	ctx := grpc_ctxtags.SetInContext(context.Background(), callerTags)
+	require.NoError(t, interceptor(ctx, "/mwitkow.testproto.TestService/Ping", goodPing, nil, nil, invoker), "the second attempt should succeed")
+
+	require.NotNil(t, callTags)
+	assert.EqualValues(t, 2, callTags.Values()["grpc.retry.attempts"], "the tags must contain the attempts")
+	assert.Equal(t, "DataLoss", callTags.Values()["grpc.retry.last_code"], "the tags must contain the last failure")
+	assert.Equal(t, map[string]interface{}{"grpc.request.value": "something"}, callerTags.Values(), "the tags of the caller must not be altered")
+}
+
 func (s *RetrySuite) TestUnary_PerCallDeadline_Succeeds() {
 	// This tests 5 requests, with first 4 sleeping for 10 millisecond, and the retry logic firing
 	// a retry call with a 5 millisecond deadline. The 5th one doesn't sleep and succeeds.
@@ -289,6 +532,188 @@ func (s *RetrySuite) TestServerStream_CallRetrySucceeds() {
 	<-restarted
 }
 
+func (s *RetrySuite) TestUnary_ThrottlesRetriesWhenBudgetIsExhausted() {
+	s.srv.resetFailingConfiguration(100, codes.DataLoss, noSleep) // all requests fail
+	throttler := grpc_retry.NewThrottler(4, 1)
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithMax(5), grpc_retry.WithThrottler(throttler))
+	require.Error(s.T(), err, "error must occur from the failing service")
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "failure code must come from the last attempt")
+	require.EqualValues(s.T(), 2, s.srv.requestCount(), "retries must stop once half of the tokens are used")
+
+	_, err = s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithMax(5), grpc_retry.WithThrottler(throttler))
+	require.Error(s.T(), err, "error must occur from the failing service")
+	require.EqualValues(s.T(), 3, s.srv.requestCount(), "no retries should be made while throttled")
+
+	s.srv.resetFailingConfiguration(1, codes.OK, noSleep) // all requests succeed
+	for i := 0; i < 3; i++ {
+		_, err = s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithMax(5), grpc_retry.WithThrottler(throttler))
+		require.NoError(s.T(), err, "successful calls must not be throttled")
+	}
+
+	s.srv.resetFailingConfiguration(2, codes.DataLoss, noSleep) // every second request succeeds
+	_, err = s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithMax(5), grpc_retry.WithThrottler(throttler))
+	require.NoError(s.T(), err, "successes must refill the bucket and allow retries again")
+	require.EqualValues(s.T(), 2, s.srv.requestCount(), "two requests should have been made")
+}
+
+func (s *RetrySuite) TestUnary_ThrottlesRetriesOfTimedOutAttempts() {
+	deadlinePerCall := 20 * time.Millisecond
+	s.srv.resetFailingConfiguration(100, codes.NotFound, 2*deadlinePerCall) // all requests time out
+	throttler := grpc_retry.NewThrottler(4, 1)
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithMax(5), grpc_retry.WithThrottler(throttler),
+		grpc_retry.WithPerRetryTimeout(deadlinePerCall))
+	require.Equal(s.T(), codes.DeadlineExceeded, status.Code(err), "failure code must come from the last attempt")
+	require.EqualValues(s.T(), 2, s.srv.requestCount(), "timed out attempts must use the retry budget")
+}
+
+func (s *RetrySuite) TestServerStream_ThrottlesRetriesWhenBudgetIsExhausted() {
+	s.srv.resetFailingConfiguration(100, codes.DataLoss, noSleep) // all requests fail
+	throttler := grpc_retry.NewThrottler(4, 1)
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing, grpc_retry.WithMax(5), grpc_retry.WithThrottler(throttler))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	_, err = stream.Recv()
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "failure code must come from the last attempt")
+	require.EqualValues(s.T(), 2, s.srv.requestCount(), "retries must stop once half of the tokens are used")
+}
+
+func (s *RetrySuite) TestUnary_StopsOnNegativeServerPushback() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	s.srv.setPushback("-1", nil)
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithServerPushback(true))
+	require.Error(s.T(), err, "the server asked not to retry")
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "failure code must come from the failing service")
+	require.EqualValues(s.T(), 1, s.srv.requestCount(), "one request should have been made")
+}
+
+func (s *RetrySuite) TestUnary_WaitsForServerPushbackTrailer() {
+	// The backoff is longer than the context deadline, so the calls only succeed if the pushback is used.
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	s.srv.setPushback("1", nil)
+	out, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithServerPushback(true), grpc_retry.WithBackoff(grpc_retry.BackoffLinear(time.Hour)))
+	require.NoError(s.T(), err, "the third invocation should succeed")
+	require.NotNil(s.T(), out, "Pong must be not nil")
+	require.EqualValues(s.T(), 3, s.srv.requestCount(), "three requests should have been made")
+}
+
+func (s *RetrySuite) TestUnary_WaitsForServerRetryInfo() {
+	retryDelay := time.Millisecond
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	s.srv.setPushback("", &retryDelay)
+	out, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithServerPushback(true), grpc_retry.WithBackoff(grpc_retry.BackoffLinear(time.Hour)))
+	require.NoError(s.T(), err, "the third invocation should succeed")
+	require.NotNil(s.T(), out, "Pong must be not nil")
+	require.EqualValues(s.T(), 3, s.srv.requestCount(), "three requests should have been made")
+}
+
+func (s *RetrySuite) TestUnary_IgnoresServerPushbackByDefault() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	s.srv.setPushback("-1", nil)
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "the third invocation should succeed")
+	require.EqualValues(s.T(), 3, s.srv.requestCount(), "three requests should have been made")
+}
+
+func (s *RetrySuite) TestUnary_CapsServerPushback() {
+	// The server asks for an hour, longer than the context deadline, so the calls only succeed if the delay is capped.
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	s.srv.setPushback("3600000", nil)
+	out, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_retry.WithServerPushback(true), grpc_retry.WithMaxServerPushback(time.Millisecond))
+	require.NoError(s.T(), err, "the third invocation should succeed")
+	require.NotNil(s.T(), out, "Pong must be not nil")
+	require.EqualValues(s.T(), 3, s.srv.requestCount(), "three requests should have been made")
+}
+
+func (s *RetrySuite) TestServerStream_StopsOnNegativeServerPushback() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	s.srv.setPushback("-1", nil)
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing, grpc_retry.WithServerPushback(true))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	_, err = stream.Recv()
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "failure code must come from the failing service")
+	require.EqualValues(s.T(), 1, s.srv.requestCount(), "one request should have been made")
+}
+
+func (s *RetrySuite) TestServerStream_WaitsForServerPushbackTrailer() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep)
+	s.srv.setPushback("1", nil)
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing, grpc_retry.WithServerPushback(true), grpc_retry.WithBackoff(grpc_retry.BackoffLinear(time.Hour)))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	s.assertPingListWasCorrect(stream)
+	require.EqualValues(s.T(), 3, s.srv.requestCount(), "three requests should have been made")
+}
+
+func (s *RetrySuite) TestBidiStream_SucceedsOnRetriableError() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep) // see retriable_errors
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	for i := 0; i < 3; i++ {
+		require.NoError(s.T(), stream.Send(goodPing), "sending must succeed while the stream can be retried")
+	}
+	require.NoError(s.T(), stream.CloseSend(), "no error on close send")
+	count := 0
+	for {
+		pong, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "no errors during receive on client side")
+		require.Equal(s.T(), goodPing.Value, pong.Value, "the returned pong contained the outgoing ping")
+		count++
+	}
+	require.EqualValues(s.T(), 3, count, "all the buffered pings must have been replayed")
+	require.EqualValues(s.T(), 3, s.srv.requestCount(), "three requests should have been made")
+}
+
+func (s *RetrySuite) TestBidiStream_NotRetriedWhenReplayBufferExceeded() {
+	s.srv.resetFailingConfiguration(3, codes.DataLoss, noSleep) // see retriable_errors
+	stream, err := s.Client.PingStream(s.SimpleCtx(), grpc_retry.WithReplayBuffer(1, 0))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	stream.Send(goodPing)
+	stream.Send(goodPing)
+	stream.CloseSend()
+	_, err = stream.Recv()
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "failure code must come from the failing service")
+	require.EqualValues(s.T(), 1, s.srv.requestCount(), "one request should have been made")
+}
+
+func (s *RetrySuite) TestBidiStream_SendFailsOnceNotRetriable() {
+	s.srv.resetFailingConfiguration(3, codes.Internal, noSleep) // Internal is not retriable
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	_, err = stream.Recv()
+	require.Equal(s.T(), codes.Internal, status.Code(err), "failure code must come from the failing service")
+	require.Equal(s.T(), io.EOF, stream.Send(goodPing), "sending on a failed stream must return io.EOF")
+	require.EqualValues(s.T(), 1, s.srv.requestCount(), "one request should have been made")
+}
+
+func (s *RetrySuite) TestBidiStream_SendFailsOnceDefaultReplayBufferExceeded() {
+	s.srv.resetFailingConfiguration(100, codes.DataLoss, noSleep) // all requests fail
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	sent := uint(0)
+	for ; sent <= 10*grpc_retry.DefaultReplayBufferMessages; sent++ {
+		if err = stream.Send(goodPing); err != nil {
+			break
+		}
+	}
+	require.Equal(s.T(), io.EOF, err, "sending on a failed stream must stop once the replay buffer is exceeded")
+	require.True(s.T(), sent > grpc_retry.DefaultReplayBufferMessages, "the buffered messages must not be reported before the buffer is exceeded")
+	_, err = stream.Recv()
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "the stream must not be retried once the buffer is exceeded")
+}
+
+func (s *RetrySuite) TestBidiStream_NotRetriedAfterFirstResponse() {
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	require.NoError(s.T(), stream.Send(goodPing), "sending must succeed")
+	_, err = stream.Recv()
+	require.NoError(s.T(), err, "the first response must be received")
+	require.NoError(s.T(), stream.Send(&pb_testproto.PingRequest{Value: "fail", ErrorCodeReturned: uint32(codes.DataLoss)}))
+	_, err = stream.Recv()
+	require.Equal(s.T(), codes.DataLoss, status.Code(err), "the committed stream must not be retried")
+	require.EqualValues(s.T(), 1, s.srv.requestCount(), "one request should have been made")
+}
+
 func (s *RetrySuite) assertPingListWasCorrect(stream pb_testproto.TestService_PingListClient) {
 	count := 0
 	for {
@@ -304,6 +729,164 @@ func (s *RetrySuite) assertPingListWasCorrect(stream pb_testproto.TestService_Pi
 	require.EqualValues(s.T(), grpc_testing.ListResponseCount, count, "should have received all ping items")
 }
 
+// resumableService streams responses starting at the offset sent in the request value, and breaks the
+// first stream after sending breakAfter responses.
+type resumableService struct {
+	pb_testproto.TestServiceServer
+	breakAfter int
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *resumableService) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = 0
+}
+
+func (s *resumableService) PingList(ping *pb_testproto.PingRequest, stream pb_testproto.TestService_PingListServer) error {
+	s.mu.Lock()
+	s.calls++
+	calls := s.calls
+	s.mu.Unlock()
+	offset, _ := strconv.Atoi(ping.Value)
+	for i := offset; i < grpc_testing.ListResponseCount; i++ {
+		if calls == 1 && i == s.breakAfter {
+			return status.Error(codes.Unavailable, "stream broken")
+		}
+		stream.Send(&pb_testproto.PingResponse{Value: ping.Value, Counter: int32(i)})
+	}
+	return nil
+}
+
+func TestResumeSuite(t *testing.T) {
+	service := &resumableService{
+		TestServiceServer: &grpc_testing.TestPingService{T: t},
+		breakAfter:        grpc_testing.ListResponseCount / 2,
+	}
+	s := &ResumeSuite{
+		srv: service,
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: service,
+			ClientOpts: []grpc.DialOption{
+				grpc.WithStreamInterceptor(grpc_retry.StreamClientInterceptor(
+					grpc_retry.WithMax(3),
+					grpc_retry.WithBackoff(grpc_retry.BackoffLinear(retryTimeout)),
+				)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type ResumeSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	srv *resumableService
+}
+
+func (s *ResumeSuite) SetupTest() {
+	s.srv.reset()
+}
+
+func (s *ResumeSuite) TestServerStream_RestartsWithoutResumeFunc() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), &pb_testproto.PingRequest{Value: "0"})
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	counters := s.receiveCounters(stream)
+	require.Len(s.T(), counters, grpc_testing.ListResponseCount+s.srv.breakAfter, "the responses before the failure must be received twice")
+}
+
+func (s *ResumeSuite) TestServerStream_ContinuesWithResumeFunc() {
+	resume := func(ctx context.Context, req interface{}, lastReceived interface{}) (interface{}, error) {
+		next := lastReceived.(*pb_testproto.PingResponse).Counter + 1
+		return &pb_testproto.PingRequest{Value: strconv.Itoa(int(next))}, nil
+	}
+	stream, err := s.Client.PingList(s.SimpleCtx(), &pb_testproto.PingRequest{Value: "0"}, grpc_retry.WithStreamResume(resume))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	counters := s.receiveCounters(stream)
+	require.Len(s.T(), counters, grpc_testing.ListResponseCount, "every response must be received once")
+	for i, counter := range counters {
+		require.EqualValues(s.T(), i, counter, "the responses must be received in order")
+	}
+}
+
+func (s *ResumeSuite) TestServerStream_FailsOnResumeFuncError() {
+	resume := func(ctx context.Context, req interface{}, lastReceived interface{}) (interface{}, error) {
+		return nil, status.Error(codes.FailedPrecondition, "cannot resume")
+	}
+	stream, err := s.Client.PingList(s.SimpleCtx(), &pb_testproto.PingRequest{Value: "0"}, grpc_retry.WithStreamResume(resume))
+	require.NoError(s.T(), err, "establishing the connection must always succeed")
+	for i := 0; i < s.srv.breakAfter; i++ {
+		_, err = stream.Recv()
+		require.NoError(s.T(), err, "the responses before the failure must be received")
+	}
+	_, err = stream.Recv()
+	require.Equal(s.T(), codes.FailedPrecondition, status.Code(err), "failure code must come from the ResumeFunc")
+}
+
+func (s *ResumeSuite) receiveCounters(stream pb_testproto.TestService_PingListClient) []int32 {
+	var counters []int32
+	for {
+		pong, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "no errors during receive on client side")
+		counters = append(counters, pong.Counter)
+	}
+	return counters
+}
+
+// singleResponseStream is a client stream that receives a single response, as in client-streaming calls.
+type singleResponseStream struct {
+	grpc.ClientStream
+}
+
+func (s *singleResponseStream) SendMsg(m interface{}) error { return nil }
+func (s *singleResponseStream) CloseSend() error            { return nil }
+func (s *singleResponseStream) RecvMsg(m interface{}) error { return nil }
+
+func TestClientStream_NotifiesObserversOnResponse(t *testing.T) {
+	for _, max := range []uint{0, 3} {
+		observer := &recordingObserver{}
+		interceptor := grpc_retry.StreamClientInterceptor(grpc_retry.WithMax(max), grpc_retry.WithObservers(observer.observe))
+		streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &singleResponseStream{}, nil
+		}
+		desc := &grpc.StreamDesc{ClientStreams: true}
+		stream, err := interceptor(context.Background(), desc, nil, "/mwitkow.testproto.TestService/PingStream", streamer)
+		require.NoError(t, err, "establishing the stream must succeed")
+		require.NoError(t, stream.SendMsg(goodPing), "sending must succeed")
+		require.NoError(t, stream.CloseSend(), "closing must succeed")
+		require.NoError(t, stream.RecvMsg(&pb_testproto.PingResponse{}), "receiving the response must succeed")
+		require.Equal(t, []string{
+			"grpc_retry.attempt_started 0 OK",
+			"grpc_retry.call_finished 0 OK",
+		}, observer.events(), "the response must finish the call with max %d", max)
+	}
+}
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	recorded []grpc_retry.Event
+}
+
+func (o *recordingObserver) observe(ctx context.Context, event grpc_retry.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.recorded = append(o.recorded, event)
+}
+
+func (o *recordingObserver) events() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var events []string
+	for _, e := range o.recorded {
+		events = append(events, fmt.Sprintf("%v %d %v", e.Type, e.Attempt, e.Code))
+	}
+	return events
+}
+
 type trackedInterceptor struct {
 	called int
 }
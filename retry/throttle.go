@@ -0,0 +1,109 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_retry
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Throttler implements client-side retry throttling as described in the gRPC retry design
+// (https://github.com/grpc/proposal/blob/master/A6-client-retries.md#throttling-retry-attempts-and-hedged-rpcs).
+//
+// A Throttler keeps a token bucket per target (`grpc.ClientConn.Target()`). Every failed attempt
+// (one that returned a retriable code, or that exceeded the `WithPerRetryTimeout` deadline) removes a
+// token from the bucket, and every successful call adds `tokenRatio` tokens to it, up to `maxTokens`.
+// Retries are only attempted while the bucket holds more than half of `maxTokens`. The first attempt
+// of a call is never throttled. Buckets are dropped once they are full again, so the memory used by
+// a Throttler doesn't grow with the number of targets.
+//
+// The same Throttler should be passed to both the unary and stream interceptors of a `grpc.ClientConn`
+// so that they share the same budget.
+type Throttler struct {
+	maxTokens  float64
+	tokenRatio float64
+
+	mu sync.Mutex
+	// tokens holds the tokens of the targets whose bucket isn't full. Full buckets are evicted, so that
+	// only the targets with recent failures are kept.
+	tokens map[string]float64
+}
+
+// NewThrottler creates a Throttler with the given bucket size and refill ratio.
+//
+// For example maxTokens=10 and tokenRatio=0.1 disables retries once the failure rate goes over 10%.
+func NewThrottler(maxTokens uint, tokenRatio float64) *Throttler {
+	return &Throttler{
+		maxTokens:  float64(maxTokens),
+		tokenRatio: tokenRatio,
+		tokens:     make(map[string]float64),
+	}
+}
+
+func (t *Throttler) bucketFor(cc *grpc.ClientConn) *throttleBucket {
+	if t == nil {
+		return nil
+	}
+	target := ""
+	if cc != nil {
+		target = cc.Target()
+	}
+	return &throttleBucket{throttler: t, target: target}
+}
+
+// tokensLocked returns the tokens of a target, which has a full bucket unless it is in the tokens map.
+func (t *Throttler) tokensLocked(target string) float64 {
+	if tokens, ok := t.tokens[target]; ok {
+		return tokens
+	}
+	return t.maxTokens
+}
+
+func (t *Throttler) setTokensLocked(target string, tokens float64) {
+	if tokens >= t.maxTokens {
+		delete(t.tokens, target)
+		return
+	}
+	if tokens < 0 {
+		tokens = 0
+	}
+	t.tokens[target] = tokens
+}
+
+// throttleBucket is the token bucket of a single target. A nil bucket never throttles.
+type throttleBucket struct {
+	throttler *Throttler
+	target    string
+}
+
+func (b *throttleBucket) allowRetry() bool {
+	if b == nil {
+		return true
+	}
+	t := b.throttler
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tokensLocked(b.target) > t.maxTokens/2
+}
+
+func (b *throttleBucket) recordSuccess() {
+	if b == nil {
+		return
+	}
+	t := b.throttler
+	t.mu.Lock()
+	t.setTokensLocked(b.target, t.tokensLocked(b.target)+t.tokenRatio)
+	t.mu.Unlock()
+}
+
+func (b *throttleBucket) recordFailure() {
+	if b == nil {
+		return
+	}
+	t := b.throttler
+	t.mu.Lock()
+	t.setTokensLocked(b.target, t.tokensLocked(b.target)-1)
+	t.mu.Unlock()
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_ctxtags
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryClientInterceptor returns a new unary client interceptor that sets the values for the tags of outbound calls.
+//
+// Each call gets its own Tags, which inherit the tags of the caller's context, so that the tags set for the call
+// (`grpc.target`, `grpc.full_method` and the extracted fields) don't alter the tags of the caller, e.g. a
+// server handler. The tags listed in `WithPropagatedTags` are sent to the server as outgoing metadata.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		newCtx := newClientTagsForCtx(propagateTags(ctx, o), cc, method, o)
+		if o.requestFieldsFunc != nil {
+			setRequestFieldTags(newCtx, o.requestFieldsFunc, method, req)
+		}
+		err := invoker(newCtx, method, req, reply, cc, callOpts...)
+		if o.responseFieldsFunc != nil && err == nil {
+			setResponseFieldTags(newCtx, o.responseFieldsFunc, method, reply)
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a new streaming client interceptor that sets the values for the tags of outbound calls.
+//
+// The tags are set as for UnaryClientInterceptor. Request fields are extracted from the single request of
+// server-streams. For client and bidirectional streams, they are extracted from the first message with
+// `WithFieldExtractorForInitialReq`, or from every message sent with `WithFieldExtractorForStreamedReqs`.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		newCtx := newClientTagsForCtx(propagateTags(ctx, o), cc, method, o)
+		clientStream, err := streamer(newCtx, desc, cc, method, callOpts...)
+		if err != nil || o.requestFieldsFunc == nil && o.responseFieldsFunc == nil {
+			return clientStream, err
+		}
+		return &wrappedClientStream{ClientStream: clientStream, desc: desc, method: method, opts: o, ctx: newCtx, initial: true}, nil
+	}
+}
+
+// wrappedClientStream is a thin wrapper around grpc.ClientStream that extracts log fields from the messages sent and received.
+type wrappedClientStream struct {
+	grpc.ClientStream
+	desc           *grpc.StreamDesc
+	method         string
+	opts           *options
+	ctx            context.Context
+	initial        bool
+	streamedFields streamFieldTags
+}
+
+func (w *wrappedClientStream) SendMsg(m interface{}) error {
+	if w.opts.requestFieldsFunc != nil && w.desc.ClientStreams && w.opts.streamFieldsMode != 0 {
+		err := w.ClientStream.SendMsg(m)
+		if err == nil {
+			w.streamedFields.set(Extract(w.ctx), w.opts, w.method, m)
+		}
+		return err
+	}
+	// We only do log fields extraction on the single-request of a server-side stream.
+	if w.opts.requestFieldsFunc != nil && w.initial && (!w.desc.ClientStreams || w.opts.requestFieldsFromInitial) {
+		w.initial = false
+		setRequestFieldTags(w.ctx, w.opts.requestFieldsFunc, w.method, m)
+	}
+	return w.ClientStream.SendMsg(m)
+}
+
+func (w *wrappedClientStream) RecvMsg(m interface{}) error {
+	err := w.ClientStream.RecvMsg(m)
+	if w.opts.responseFieldsFunc != nil && err == nil {
+		setResponseFieldTags(w.ctx, w.opts.responseFieldsFunc, w.method, m)
+	}
+	return err
+}
+
+// newClientTagsForCtx sets new tags in the context for an outbound call, with a copy of the tags of the caller.
+func newClientTagsForCtx(ctx context.Context, cc *grpc.ClientConn, method string, o *options) context.Context {
+	t := o.newTags()
+	Range(Extract(ctx), func(key string, value interface{}) bool {
+		t.Set(key, value)
+		return true
+	})
+	if cc != nil {
+		t.Set("grpc.target", cc.Target())
+	}
+	t.Set("grpc.full_method", method)
+	return SetInContext(ctx, t)
+}
@@ -0,0 +1,155 @@
+package grpc_ctxtags_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+)
+
+// clientTagsCapture records the tags of the last outbound call, as seen by the interceptors chained after grpc_ctxtags.
+type clientTagsCapture struct {
+	mu   sync.Mutex
+	tags grpc_ctxtags.Tags
+}
+
+func (c *clientTagsCapture) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	c.mu.Lock()
+	c.tags = grpc_ctxtags.Extract(ctx)
+	c.mu.Unlock()
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (c *clientTagsCapture) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	c.mu.Lock()
+	c.tags = grpc_ctxtags.Extract(ctx)
+	c.mu.Unlock()
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+func (c *clientTagsCapture) values() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tags.Values()
+}
+
+func TestClientTaggingSuite(t *testing.T) {
+	capture := &clientTagsCapture{}
+	opts := []grpc_ctxtags.Option{
+		grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor),
+		grpc_ctxtags.WithResponseFieldExtractor(grpc_ctxtags.CodeGenResponseFieldExtractor),
+	}
+	s := &ClientTaggingSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &grpc_testing.TestPingService{T: t},
+			ClientOpts: []grpc.DialOption{
+				grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(grpc_ctxtags.UnaryClientInterceptor(opts...), capture.unary)),
+				grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(grpc_ctxtags.StreamClientInterceptor(opts...), capture.stream)),
+			},
+		},
+		capture: capture,
+	}
+	suite.Run(t, s)
+}
+
+type ClientTaggingSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	capture *clientTagsCapture
+}
+
+func (s *ClientTaggingSuite) TestPing_TagsCallAndKeepsCallerTags() {
+	callerTags := grpc_ctxtags.NewTags().Set("tenant.id", "acme")
+	_, err := s.Client.Ping(grpc_ctxtags.SetInContext(s.SimpleCtx(), callerTags), goodPing)
+	require.NoError(s.T(), err, "must not be an error on a successful call")
+
+	tags := s.capture.values()
+	assert.Equal(s.T(), "acme", tags["tenant.id"], "the tags of the call should inherit the caller's tags")
+	assert.NotEmpty(s.T(), tags["grpc.target"], "the tags should contain the target")
+	assert.Equal(s.T(), "/mwitkow.testproto.TestService/Ping", tags["grpc.full_method"], "the tags should contain the method")
+	assert.Equal(s.T(), "something", tags["grpc.request.value"], "the tags should contain the request fields")
+	assert.EqualValues(s.T(), 42, tags["grpc.response.counter"], "the tags should contain the response fields")
+	assert.Equal(s.T(), map[string]interface{}{"tenant.id": "acme"}, callerTags.Values(), "the caller's tags must not be altered")
+}
+
+func (s *ClientTaggingSuite) TestPing_WithoutCallerTags() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "must not be an error on a successful call")
+
+	tags := s.capture.values()
+	assert.Equal(s.T(), "/mwitkow.testproto.TestService/Ping", tags["grpc.full_method"], "calls without caller tags should get their own")
+}
+
+func (s *ClientTaggingSuite) TestPingList_TagsRequestAndLastResponse() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+
+	tags := s.capture.values()
+	assert.Equal(s.T(), "/mwitkow.testproto.TestService/PingList", tags["grpc.full_method"], "the tags should contain the method")
+	assert.Equal(s.T(), "something", tags["grpc.request.value"], "the tags should contain the fields of the request")
+	assert.EqualValues(s.T(), grpc_testing.ListResponseCount-1, tags["grpc.response.counter"], "the tags should contain the fields of the last response")
+}
+
+func (s *ClientTaggingSuite) TestPingStream_DoesNotTagRequests() {
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	require.NoError(s.T(), stream.Send(&pb_testproto.PingRequest{Value: "first"}), "sending should not fail")
+	_, err = stream.Recv()
+	require.NoError(s.T(), err, "reading stream should not fail")
+	require.NoError(s.T(), stream.CloseSend(), "closing should not fail")
+
+	assert.NotContains(s.T(), s.capture.values(), "grpc.request.value", "client streams must not be tagged without WithFieldExtractorForInitialReq")
+}
+
+func TestStreamedClientTaggingSuite(t *testing.T) {
+	capture := &clientTagsCapture{}
+	opts := []grpc_ctxtags.Option{
+		grpc_ctxtags.WithFieldExtractorForStreamedReqs(grpc_ctxtags.CodeGenRequestFieldExtractor, grpc_ctxtags.StreamFieldsAccumulated, 2),
+	}
+	s := &StreamedClientTaggingSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &grpc_testing.TestPingService{T: t},
+			ClientOpts: []grpc.DialOption{
+				grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(grpc_ctxtags.StreamClientInterceptor(opts...), capture.stream)),
+			},
+		},
+		capture: capture,
+	}
+	suite.Run(t, s)
+}
+
+type StreamedClientTaggingSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	capture *clientTagsCapture
+}
+
+func (s *StreamedClientTaggingSuite) TestPingStream_TagsEveryMessageSent() {
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for _, value := range []string{"a", "b", "a", "c"} {
+		require.NoError(s.T(), stream.Send(&pb_testproto.PingRequest{Value: value}), "sending should not fail")
+		_, err = stream.Recv()
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	require.NoError(s.T(), stream.CloseSend(), "closing should not fail")
+
+	tags := s.capture.values()
+	assert.EqualValues(s.T(), 4, tags["grpc.request.messages"], "the messages sent must be counted")
+	assert.Equal(s.T(), []interface{}{"a", "b"}, tags["grpc.request.value"], "the distinct values must be accumulated up to the limit")
+	assert.Equal(s.T(), true, tags["grpc.request.value.truncated"], "values over the limit must be reported")
+}
@@ -2,6 +2,7 @@ package grpc_ctxtags
 
 import (
 	"context"
+	"sync"
 )
 
 type ctxMarker struct{}
@@ -15,17 +16,57 @@ var (
 )
 
 // Tags is the interface used for storing request tags between Context calls.
-// The default implementation is *not* thread safe, and should be handled only in the context of the request.
+// The default implementation is thread safe, so tags can be set from goroutines spawned by the handler while
+// other middleware reads them.
 type Tags interface {
 	// Set sets the given key in the metadata tags.
 	Set(key string, value interface{}) Tags
 	// Has checks if the given key exists.
 	Has(key string) bool
-	// Values returns a map of key to values.
-	// Do not modify the underlying map, please use Set instead.
+	// Values returns a snapshot of the tags as a map of key to values.
+	// The map is a copy, modifying it doesn't change the tags.
 	Values() map[string]interface{}
 }
 
+// TagsReader is implemented by the Tags of this package to read tags without copying all of them.
+// Other implementations of Tags may not implement it, use Get and Range to read any Tags.
+type TagsReader interface {
+	// Get returns the value of the given key, and whether it exists.
+	Get(key string) (interface{}, bool)
+	// Range calls f for each key and value, until f returns false.
+	// The tags must not be modified from f.
+	Range(f func(key string, value interface{}) bool)
+}
+
+// TagsDeleter is implemented by the Tags of this package to remove tags.
+type TagsDeleter interface {
+	// Delete removes the given key from the metadata tags.
+	Delete(key string) Tags
+}
+
+// Get returns the value of the given key in tags, and whether it exists.
+func Get(t Tags, key string) (interface{}, bool) {
+	if r, ok := t.(TagsReader); ok {
+		return r.Get(key)
+	}
+	v, ok := t.Values()[key]
+	return v, ok
+}
+
+// Range calls f for each key and value of tags, until f returns false.
+func Range(t Tags, f func(key string, value interface{}) bool) {
+	if r, ok := t.(TagsReader); ok {
+		r.Range(f)
+		return
+	}
+	for k, v := range t.Values() {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// mapTags is the unsynchronized implementation of Tags.
 type mapTags struct {
 	values map[string]interface{}
 }
@@ -40,8 +81,77 @@ func (t *mapTags) Has(key string) bool {
 	return ok
 }
 
+func (t *mapTags) Get(key string) (interface{}, bool) {
+	v, ok := t.values[key]
+	return v, ok
+}
+
+func (t *mapTags) Delete(key string) Tags {
+	delete(t.values, key)
+	return t
+}
+
+func (t *mapTags) Range(f func(key string, value interface{}) bool) {
+	for k, v := range t.values {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
 func (t *mapTags) Values() map[string]interface{} {
-	return t.values
+	values := make(map[string]interface{}, len(t.values))
+	for k, v := range t.values {
+		values[k] = v
+	}
+	return values
+}
+
+// syncTags guards a mapTags with a lock.
+type syncTags struct {
+	mu   sync.RWMutex
+	tags mapTags
+}
+
+func (t *syncTags) Set(key string, value interface{}) Tags {
+	t.mu.Lock()
+	t.tags.Set(key, value)
+	t.mu.Unlock()
+	return t
+}
+
+func (t *syncTags) Has(key string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tags.Has(key)
+}
+
+func (t *syncTags) Get(key string) (interface{}, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tags.Get(key)
+}
+
+func (t *syncTags) Delete(key string) Tags {
+	t.mu.Lock()
+	t.tags.Delete(key)
+	t.mu.Unlock()
+	return t
+}
+
+// Range iterates over a snapshot of the tags, so that f can't deadlock by using them.
+func (t *syncTags) Range(f func(key string, value interface{}) bool) {
+	for k, v := range t.Values() {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+func (t *syncTags) Values() map[string]interface{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tags.Values()
 }
 
 type noopTags struct{}
@@ -54,6 +164,17 @@ func (t *noopTags) Has(key string) bool {
 	return false
 }
 
+func (t *noopTags) Get(key string) (interface{}, bool) {
+	return nil, false
+}
+
+func (t *noopTags) Delete(key string) Tags {
+	return t
+}
+
+func (t *noopTags) Range(f func(key string, value interface{}) bool) {
+}
+
 func (t *noopTags) Values() map[string]interface{} {
 	return nil
 }
@@ -73,6 +194,13 @@ func SetInContext(ctx context.Context, tags Tags) context.Context {
 	return context.WithValue(ctx, ctxMarkerKey, tags)
 }
 
+// NewTags returns an empty, thread safe Tags.
 func NewTags() Tags {
+	return &syncTags{tags: mapTags{values: make(map[string]interface{})}}
+}
+
+// NewUnsynchronizedTags returns an empty Tags that must only be used from one goroutine at a time.
+// It avoids the cost of locking for requests that are handled by a single goroutine.
+func NewUnsynchronizedTags() Tags {
 	return &mapTags{values: make(map[string]interface{})}
 }
@@ -0,0 +1,166 @@
+package grpc_ctxtags_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags_SetGetDelete(t *testing.T) {
+	for name, tags := range map[string]grpc_ctxtags.Tags{
+		"synchronized":   grpc_ctxtags.NewTags(),
+		"unsynchronized": grpc_ctxtags.NewUnsynchronizedTags(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			tags.Set("a", 1).Set("b", "two")
+			assert.True(t, tags.Has("a"))
+			v, ok := grpc_ctxtags.Get(tags, "b")
+			assert.True(t, ok)
+			assert.Equal(t, "two", v)
+
+			tags.(grpc_ctxtags.TagsDeleter).Delete("a")
+			assert.False(t, tags.Has("a"))
+			_, ok = grpc_ctxtags.Get(tags, "a")
+			assert.False(t, ok)
+			assert.Equal(t, map[string]interface{}{"b": "two"}, tags.Values())
+		})
+	}
+}
+
+func TestTags_ValuesIsASnapshot(t *testing.T) {
+	tags := grpc_ctxtags.NewTags().Set("a", 1)
+	values := tags.Values()
+	values["b"] = 2
+	tags.Set("c", 3)
+	assert.False(t, tags.Has("b"), "modifying the snapshot must not change the tags")
+	assert.NotContains(t, values, "c", "setting tags must not change a previous snapshot")
+}
+
+func TestTags_Range(t *testing.T) {
+	tags := grpc_ctxtags.NewTags().Set("a", 1).Set("b", 2).Set("c", 3)
+	seen := map[string]interface{}{}
+	grpc_ctxtags.Range(tags, func(key string, value interface{}) bool {
+		seen[key] = value
+		return true
+	})
+	assert.Equal(t, tags.Values(), seen)
+
+	calls := 0
+	grpc_ctxtags.Range(tags, func(key string, value interface{}) bool {
+		calls++
+		return false
+	})
+	assert.Equal(t, 1, calls, "range must stop when f returns false")
+}
+
+func TestTags_ConcurrentUse(t *testing.T) {
+	tags := grpc_ctxtags.NewTags()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d-%d", i, j)
+				tags.Set(key, j)
+				tags.Has(key)
+				for range tags.Values() {
+				}
+				grpc_ctxtags.Range(tags, func(string, interface{}) bool { return true })
+				tags.(grpc_ctxtags.TagsDeleter).Delete(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Empty(t, tags.Values())
+}
+
+func TestTypedTags(t *testing.T) {
+	tags := grpc_ctxtags.NewTags()
+	grpc_ctxtags.SetString(tags, "string", "value")
+	grpc_ctxtags.SetInt64(tags, "int64", -42)
+	grpc_ctxtags.SetBool(tags, "bool", true)
+	grpc_ctxtags.SetDuration(tags, "duration", 3*time.Second)
+	tags.Set("int", 7).Set("uint32", uint32(8)).Set("huge", uint64(math.MaxUint64))
+
+	s, ok := grpc_ctxtags.GetString(tags, "string")
+	require.True(t, ok)
+	assert.Equal(t, "value", s)
+	i, ok := grpc_ctxtags.GetInt64(tags, "int64")
+	require.True(t, ok)
+	assert.Equal(t, int64(-42), i)
+	i, ok = grpc_ctxtags.GetInt64(tags, "int")
+	require.True(t, ok)
+	assert.Equal(t, int64(7), i)
+	i, ok = grpc_ctxtags.GetInt64(tags, "uint32")
+	require.True(t, ok)
+	assert.Equal(t, int64(8), i)
+	b, ok := grpc_ctxtags.GetBool(tags, "bool")
+	require.True(t, ok)
+	assert.True(t, b)
+	d, ok := grpc_ctxtags.GetDuration(tags, "duration")
+	require.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+
+	_, ok = grpc_ctxtags.GetInt64(tags, "huge")
+	assert.False(t, ok, "values overflowing an int64 must not be returned")
+	_, ok = grpc_ctxtags.GetString(tags, "int64")
+	assert.False(t, ok, "values of another type must not be returned")
+	_, ok = grpc_ctxtags.GetBool(tags, "missing")
+	assert.False(t, ok, "missing values must not be returned")
+}
+
+func TestNoopTags(t *testing.T) {
+	tags := grpc_ctxtags.Extract(context.Background())
+	tags.Set("a", 1)
+	assert.False(t, tags.Has("a"))
+	_, ok := grpc_ctxtags.Get(tags, "a")
+	assert.False(t, ok)
+	grpc_ctxtags.Range(tags, func(string, interface{}) bool {
+		t.Fatal("no-op tags must not have values")
+		return true
+	})
+}
+
+// valuesOnlyTags is an implementation of Tags from outside of the package, which only has the methods of Tags.
+type valuesOnlyTags map[string]interface{}
+
+func (t valuesOnlyTags) Set(key string, value interface{}) grpc_ctxtags.Tags {
+	t[key] = value
+	return t
+}
+
+func (t valuesOnlyTags) Has(key string) bool {
+	_, ok := t[key]
+	return ok
+}
+
+func (t valuesOnlyTags) Values() map[string]interface{} {
+	return t
+}
+
+func TestTags_OtherImplementations(t *testing.T) {
+	tags := valuesOnlyTags{}
+	grpc_ctxtags.SetInt64(tags, "a", 1)
+	_, isReader := grpc_ctxtags.Tags(tags).(grpc_ctxtags.TagsReader)
+	require.False(t, isReader)
+
+	i, ok := grpc_ctxtags.GetInt64(tags, "a")
+	require.True(t, ok, "tags must be read from their values")
+	assert.Equal(t, int64(1), i)
+	_, ok = grpc_ctxtags.Get(tags, "b")
+	assert.False(t, ok)
+	seen := map[string]interface{}{}
+	grpc_ctxtags.Range(tags, func(key string, value interface{}) bool {
+		seen[key] = value
+		return true
+	})
+	assert.Equal(t, map[string]interface{}{"a": int64(1)}, seen)
+}
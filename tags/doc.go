@@ -12,10 +12,39 @@ For unary and server-streaming methods, pass in the `WithFieldExtractor` option.
 use `WithFieldExtractorForInitialReq` which will extract the tags from the first message passed from client to server.
 Note the tags will not be modified for subsequent requests, so this option only makes sense when the initial message
 establishes the meta-data for the stream.
+To observe long-lived streams, `WithFieldExtractorForStreamedReqs` extracts the tags from every message instead, either
+indexed by message (`grpc.request.<n>.<field_name>`) or accumulating the distinct values of each field, with a limit on
+the number of messages or values tagged. Both options apply to the messages sent by the client interceptors as well.
+
+The fields to extract can be selected with the `(grpc_middleware.logfield.log_field)` option of
+`tags/logfield/logfield.proto` and `ProtoOptionRequestFieldExtractor`, with hand-written `ExtractRequestFields` methods
+and `CodeGenRequestFieldExtractor`, or with Go struct tags and `TagBasedRequestFieldExtractor`.
+
+You can also extract tags (in `grpc.response.<field_name>`) from the messages sent back to the client with the
+`WithResponseFieldExtractor` option, e.g. the ID of a created resource. The tags of unary methods are set once the
+handler returns, and the tags of streaming methods as each message is sent. Interceptors chained after `grpc_ctxtags`
+share its `Tags`, so they see the response tags once the handler they wrap has returned.
 
 If a user doesn't use the interceptors that initialize the `Tags` object, all operations following from an `Extract(ctx)`
 will be no-ops. This is to ensure that code doesn't panic if the interceptors weren't used.
 
+Tags can also be extracted from the incoming metadata of requests (in `grpc.request.metadata.<key>`) with the
+`WithMetadataExtractor` option, e.g. using `MetadataKeysExtractor` to select, rename and redact keys. The
+`WithCallInfoTags` option tags the authority, content type, compression and deadline of requests.
+
+On the client side, `UnaryClientInterceptor` and `StreamClientInterceptor` give every outbound call its own tags, which
+inherit the tags of the caller and add `grpc.target`, `grpc.full_method` and the extracted fields, so that client-side
+logging and tracing can use them without altering the tags of the caller.
+
+Tags can flow through a call graph: the tags listed in `WithPropagatedTags` are sent by `UnaryClientInterceptor` and
+`StreamClientInterceptor` as metadata prefixed with `x-tag-`, and set back as tags by the server interceptors given the
+same option.
+
+Tags are thread safe by default, so that handlers can set them from the goroutines they spawn while logging middleware
+reads them. `Values()` returns a snapshot of the tags, `Get` and `Range` read them without copying all of them, and
+`GetString`, `GetInt64`, `GetBool` and `GetDuration` read typed values back. The Tags of this package also implement
+`TagsReader` and `TagsDeleter`, which other implementations of `Tags` don't have to.
+
 Tags fields are typed, and shallow and should follow the OpenTracing semantics convention:
 https://github.com/opentracing/specification/blob/master/semantic_conventions.md
 */
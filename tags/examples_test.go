@@ -26,3 +26,20 @@ func Example_initialisationWithOptions() {
 		grpc.UnaryInterceptor(grpc_ctxtags.UnaryServerInterceptor(opts...)),
 	)
 }
+
+// Example of propagating the tenant ID of requests to the servers they call.
+func Example_propagation() {
+	opts := []grpc_ctxtags.Option{
+		grpc_ctxtags.WithPropagatedTags("tenant.id"),
+	}
+	// The server imports the tenant ID sent by its clients...
+	_ = grpc.NewServer(
+		grpc.StreamInterceptor(grpc_ctxtags.StreamServerInterceptor(opts...)),
+		grpc.UnaryInterceptor(grpc_ctxtags.UnaryServerInterceptor(opts...)),
+	)
+	// ...and sends it along with the calls made by its handlers.
+	_, _ = grpc.Dial("myservice:8080",
+		grpc.WithStreamInterceptor(grpc_ctxtags.StreamClientInterceptor(opts...)),
+		grpc.WithUnaryInterceptor(grpc_ctxtags.UnaryClientInterceptor(opts...)),
+	)
+}
@@ -12,6 +12,12 @@ import (
 // Keys and values will be added to the context tags of the request. If there are no fields, you should return a nil.
 type RequestFieldExtractorFunc func(fullMethod string, req interface{}) map[string]interface{}
 
+// ResponseFieldExtractorFunc is a user-provided function that extracts field information from a gRPC response.
+// It is called from tags middleware once a unary handler returns its response, and on every message sent by
+// a streaming handler. Keys and values will be added to the context tags of the request. If there are no
+// fields, you should return a nil.
+type ResponseFieldExtractorFunc func(fullMethod string, resp interface{}) map[string]interface{}
+
 type requestFieldsExtractor interface {
 	// ExtractRequestFields is a method declared on a Protobuf message that extracts fields from the interface.
 	// The values from the extracted fields should be set in the appendToMap, in order to avoid allocations.
@@ -32,6 +38,26 @@ func CodeGenRequestFieldExtractor(fullMethod string, req interface{}) map[string
 	return nil
 }
 
+type responseFieldsExtractor interface {
+	// ExtractResponseFields is a method declared on a Protobuf message that extracts fields from the interface.
+	// The values from the extracted fields should be set in the appendToMap, in order to avoid allocations.
+	ExtractResponseFields(appendToMap map[string]interface{})
+}
+
+// CodeGenResponseFieldExtractor is a function that relies on code-generated functions that export log fields from responses.
+// These are usually coming from a protoc-plugin that generates additional information based on custom field options.
+func CodeGenResponseFieldExtractor(fullMethod string, resp interface{}) map[string]interface{} {
+	if ext, ok := resp.(responseFieldsExtractor); ok {
+		retMap := make(map[string]interface{})
+		ext.ExtractResponseFields(retMap)
+		if len(retMap) == 0 {
+			return nil
+		}
+		return retMap
+	}
+	return nil
+}
+
 // TagBasedRequestFieldExtractor is a function that relies on Go struct tags to export log fields from requests.
 // These are usually coming from a protoc-plugin, such as Gogo protobuf.
 //
@@ -51,6 +77,19 @@ func TagBasedRequestFieldExtractor(tagName string) RequestFieldExtractorFunc {
 	}
 }
 
+// TagBasedResponseFieldExtractor is a function that relies on Go struct tags to export log fields from responses,
+// in the same way as TagBasedRequestFieldExtractor does for requests.
+func TagBasedResponseFieldExtractor(tagName string) ResponseFieldExtractorFunc {
+	return func(fullMethod string, resp interface{}) map[string]interface{} {
+		retMap := make(map[string]interface{})
+		reflectMessageTags(resp, retMap, tagName)
+		if len(retMap) == 0 {
+			return nil
+		}
+		return retMap
+	}
+}
+
 func reflectMessageTags(msg interface{}, existingMap map[string]interface{}, tagName string) {
 	v := reflect.ValueOf(msg)
 	// Only deal with pointers to structs.
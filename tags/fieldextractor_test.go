@@ -21,6 +21,24 @@ func TestCodeGenRequestLogFieldExtractor_ManualIsDeclared(t *testing.T) {
 	require.EqualValues(t, valMap, map[string]interface{}{"value": "my_value"})
 }
 
+func TestCodeGenResponseLogFieldExtractor_ManualIsDeclared(t *testing.T) {
+	resp := &pb_testproto.PingResponse{Value: "my_value", Counter: 42}
+	valMap := grpc_ctxtags.CodeGenResponseFieldExtractor("", resp)
+	require.EqualValues(t, map[string]interface{}{"counter": int32(42)}, valMap, "PingResponse should have a ExtractResponseFields method declared in test.manual_extractfields.pb")
+	assert.Nil(t, grpc_ctxtags.CodeGenResponseFieldExtractor("", &pb_testproto.PingRequest{}), "messages without the method must not have fields")
+}
+
+func TestTaggedResponseFieldExtractor_Pong(t *testing.T) {
+	resp := &pb_gogotestproto.PongRequest{
+		Pong: &pb_gogotestproto.Pong{
+			Id: "created-id", // logfield is pong_id
+		},
+	}
+	valMap := grpc_ctxtags.TagBasedResponseFieldExtractor("log_field")("", resp)
+	assert.EqualValues(t, map[string]interface{}{"pong_id": "created-id"}, valMap)
+	assert.Nil(t, grpc_ctxtags.TagBasedResponseFieldExtractor("log_field")("", &pb_gogotestproto.Ping{}), "messages without tagged fields must not have fields")
+}
+
 func TestTaggedRequestFiledExtractor_PingRequest(t *testing.T) {
 	req := &pb_gogotestproto.PingRequest{
 		Ping: &pb_gogotestproto.Ping{
@@ -5,6 +5,8 @@ package grpc_ctxtags
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/peer"
@@ -16,11 +18,17 @@ import (
 func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 	o := evaluateOptions(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		newCtx := newTagsForCtx(ctx)
+		newCtx := newTagsForCtx(ctx, info.FullMethod, o)
 		if o.requestFieldsFunc != nil {
 			setRequestFieldTags(newCtx, o.requestFieldsFunc, info.FullMethod, req)
 		}
-		return handler(newCtx, req)
+		resp, err := handler(newCtx, req)
+		if o.responseFieldsFunc != nil && err == nil {
+			// The Tags in newCtx are shared with the interceptors chained after this one, so they see the response
+			// tags once the handler they wrap has returned.
+			setResponseFieldTags(newCtx, o.responseFieldsFunc, info.FullMethod, resp)
+		}
+		return resp, err
 	}
 }
 
@@ -28,20 +36,21 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 	o := evaluateOptions(opts)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		newCtx := newTagsForCtx(stream.Context())
-		if o.requestFieldsFunc == nil {
+		newCtx := newTagsForCtx(stream.Context(), info.FullMethod, o)
+		if o.requestFieldsFunc == nil && o.responseFieldsFunc == nil {
 			// Short-circuit, don't do the expensive bit of allocating a wrappedStream.
 			wrappedStream := grpc_middleware.WrapServerStream(stream)
 			wrappedStream.WrappedContext = newCtx
 			return handler(srv, wrappedStream)
 		}
-		wrapped := &wrappedStream{stream, info, o, newCtx, true}
+		wrapped := &wrappedStream{ServerStream: stream, info: info, opts: o, WrappedContext: newCtx, initial: true}
 		err := handler(srv, wrapped)
 		return err
 	}
 }
 
-// wrappedStream is a thin wrapper around grpc.ServerStream that allows modifying context and extracts log fields from the initial message.
+// wrappedStream is a thin wrapper around grpc.ServerStream that allows modifying context and extracts log fields from the initial message
+// and from the messages sent.
 type wrappedStream struct {
 	grpc.ServerStream
 	info *grpc.StreamServerInfo
@@ -49,6 +58,7 @@ type wrappedStream struct {
 	// WrappedContext is the wrapper's own Context. You can assign it.
 	WrappedContext context.Context
 	initial        bool
+	streamedFields streamFieldTags
 }
 
 // Context returns the wrapper's WrappedContext, overwriting the nested grpc.ServerStream.Context()
@@ -56,8 +66,24 @@ func (w *wrappedStream) Context() context.Context {
 	return w.WrappedContext
 }
 
+func (w *wrappedStream) SendMsg(m interface{}) error {
+	if w.opts.responseFieldsFunc != nil {
+		setResponseFieldTags(w.Context(), w.opts.responseFieldsFunc, w.info.FullMethod, m)
+	}
+	return w.ServerStream.SendMsg(m)
+}
+
 func (w *wrappedStream) RecvMsg(m interface{}) error {
 	err := w.ServerStream.RecvMsg(m)
+	if w.opts.requestFieldsFunc == nil {
+		return err
+	}
+	if w.info.IsClientStream && w.opts.streamFieldsMode != 0 {
+		if err == nil {
+			w.streamedFields.set(Extract(w.Context()), w.opts, w.info.FullMethod, m)
+		}
+		return err
+	}
 	// We only do log fields extraction on the single-request of a server-side stream.
 	if !w.info.IsClientStream || w.opts.requestFieldsFromInitial && w.initial {
 		w.initial = false
@@ -67,11 +93,68 @@ func (w *wrappedStream) RecvMsg(m interface{}) error {
 	return err
 }
 
-func newTagsForCtx(ctx context.Context) context.Context {
-	t := NewTags()
+// streamFieldTags is the state of StreamFieldsMode for the messages of a client-stream or bidirectional-stream.
+type streamFieldTags struct {
+	messages       int64
+	distinctValues map[string][]interface{}
+}
+
+// set tags the fields of the next message of the stream according to StreamFieldsMode.
+func (s *streamFieldTags) set(t Tags, o *options, fullMethodName string, m interface{}) {
+	index := s.messages
+	s.messages++
+	t.Set("grpc.request.messages", s.messages)
+	valMap := o.requestFieldsFunc(fullMethodName, m)
+	switch o.streamFieldsMode {
+	case StreamFieldsIndexed:
+		if index >= int64(o.streamFieldsLimit) {
+			return
+		}
+		for k, v := range valMap {
+			t.Set(fmt.Sprintf("grpc.request.%d.%s", index, k), v)
+		}
+	case StreamFieldsAccumulated:
+		if s.distinctValues == nil {
+			s.distinctValues = make(map[string][]interface{})
+		}
+		for k, v := range valMap {
+			values := s.distinctValues[k]
+			if containsValue(values, v) {
+				continue
+			}
+			if uint(len(values)) >= o.streamFieldsLimit {
+				t.Set("grpc.request."+k+".truncated", true)
+				continue
+			}
+			values = append(values, v)
+			s.distinctValues[k] = values
+			// The tags get a copy, as they can be read while the next messages are handled.
+			t.Set("grpc.request."+k, append([]interface{}(nil), values...))
+		}
+	}
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, existing := range values {
+		if reflect.DeepEqual(existing, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTagsForCtx(ctx context.Context, fullMethodName string, o *options) context.Context {
+	t := o.newTags()
 	if peer, ok := peer.FromContext(ctx); ok {
 		t.Set("peer.address", peer.Addr.String())
 	}
+	importPropagatedTags(ctx, o, t)
+	if o.metadataFunc != nil {
+		setMetadataTags(ctx, t, o.metadataFunc, fullMethodName)
+	}
+	if o.callInfoTags {
+		setCallInfoTags(ctx, t)
+	}
 	return SetInContext(ctx, t)
 }
 
@@ -83,3 +166,12 @@ func setRequestFieldTags(ctx context.Context, f RequestFieldExtractorFunc, fullM
 		}
 	}
 }
+
+func setResponseFieldTags(ctx context.Context, f ResponseFieldExtractorFunc, fullMethodName string, resp interface{}) {
+	if valMap := f(fullMethodName, resp); valMap != nil {
+		t := Extract(ctx)
+		for k, v := range valMap {
+			t.Set("grpc.response."+k, v)
+		}
+	}
+}
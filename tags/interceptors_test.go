@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
 	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
@@ -201,3 +203,153 @@ func (s *ClientStreamedTaggingSuite) TestPingStream_WithCustomTagsFirstRequest()
 
 	assert.Equal(s.T(), count, 3)
 }
+
+// tagsCapture records the tags of the last call it intercepts, so that they can be checked once the call is over.
+type tagsCapture struct {
+	mu   sync.Mutex
+	tags grpc_ctxtags.Tags
+}
+
+func (c *tagsCapture) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	c.mu.Lock()
+	c.tags = grpc_ctxtags.Extract(ctx)
+	c.mu.Unlock()
+	return handler(ctx, req)
+}
+
+func (c *tagsCapture) stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	c.mu.Lock()
+	c.tags = grpc_ctxtags.Extract(stream.Context())
+	c.mu.Unlock()
+	return handler(srv, stream)
+}
+
+func (c *tagsCapture) values() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tags.Values()
+}
+
+func TestResponseTaggingSuite(t *testing.T) {
+	capture := &tagsCapture{}
+	opts := []grpc_ctxtags.Option{
+		grpc_ctxtags.WithResponseFieldExtractor(grpc_ctxtags.CodeGenResponseFieldExtractor),
+	}
+	s := &ResponseTaggingSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &grpc_testing.TestPingService{T: t},
+			ServerOpts: []grpc.ServerOption{
+				grpc_middleware.WithStreamServerChain(
+					grpc_ctxtags.StreamServerInterceptor(opts...),
+					capture.stream),
+				grpc_middleware.WithUnaryServerChain(
+					grpc_ctxtags.UnaryServerInterceptor(opts...),
+					capture.unary),
+			},
+		},
+		capture: capture,
+	}
+	suite.Run(t, s)
+}
+
+type ResponseTaggingSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	capture *tagsCapture
+}
+
+func (s *ResponseTaggingSuite) TestPing_TagsResponseFields() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "must not be an error on a successful call")
+
+	tags := s.capture.values()
+	assert.EqualValues(s.T(), 42, tags["grpc.response.counter"], "the tags should contain the response field")
+	assert.Contains(s.T(), tags, "peer.address", "the tags should contain a peer address")
+}
+
+func (s *ResponseTaggingSuite) TestPingList_TagsLastResponseFields() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+
+	tags := s.capture.values()
+	assert.EqualValues(s.T(), grpc_testing.ListResponseCount-1, tags["grpc.response.counter"], "the tags should contain the fields of the last response")
+}
+
+func TestStreamedRequestsTaggingSuite(t *testing.T) {
+	s := &StreamedRequestsTaggingSuite{}
+	for name, mode := range map[string]grpc_ctxtags.StreamFieldsMode{
+		"Indexed":     grpc_ctxtags.StreamFieldsIndexed,
+		"Accumulated": grpc_ctxtags.StreamFieldsAccumulated,
+	} {
+		opts := []grpc_ctxtags.Option{
+			grpc_ctxtags.WithFieldExtractorForStreamedReqs(grpc_ctxtags.CodeGenRequestFieldExtractor, mode, 2),
+		}
+		s.InterceptorTestSuite = &grpc_testing.InterceptorTestSuite{
+			TestService: &tagPingBack{&grpc_testing.TestPingService{T: t}},
+			ServerOpts: []grpc.ServerOption{
+				grpc.StreamInterceptor(grpc_ctxtags.StreamServerInterceptor(opts...)),
+				grpc.UnaryInterceptor(grpc_ctxtags.UnaryServerInterceptor(opts...)),
+			},
+		}
+		s.mode = mode
+		t.Run(name, func(t *testing.T) {
+			suite.Run(t, s)
+		})
+	}
+}
+
+type StreamedRequestsTaggingSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	mode grpc_ctxtags.StreamFieldsMode
+}
+
+// pingStream sends the values in a bidirectional stream, and returns the tags seen by the server after each of them.
+func (s *StreamedRequestsTaggingSuite) pingStream(values ...string) []map[string]interface{} {
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	var allTags []map[string]interface{}
+	for _, v := range values {
+		require.NoError(s.T(), stream.Send(&pb_testproto.PingRequest{Value: v}), "sending should not fail")
+		resp, err := stream.Recv()
+		require.NoError(s.T(), err, "reading stream should not fail")
+		allTags = append(allTags, tagsFromJson(s.T(), resp.Value))
+	}
+	require.NoError(s.T(), stream.CloseSend(), "closing should not fail")
+	return allTags
+}
+
+func (s *StreamedRequestsTaggingSuite) TestPingStream_TagsEveryMessage() {
+	allTags := s.pingStream("a", "b", "a", "c")
+	last := allTags[len(allTags)-1]
+	assert.EqualValues(s.T(), 4, last["grpc.request.messages"], "the tags should count the messages")
+	switch s.mode {
+	case grpc_ctxtags.StreamFieldsIndexed:
+		assert.Equal(s.T(), "a", allTags[0]["grpc.request.0.value"], "the first message should be tagged")
+		assert.NotContains(s.T(), allTags[0], "grpc.request.1.value", "messages not received yet must not be tagged")
+		assert.Equal(s.T(), "a", last["grpc.request.0.value"], "the first message should be tagged")
+		assert.Equal(s.T(), "b", last["grpc.request.1.value"], "the second message should be tagged")
+		assert.NotContains(s.T(), last, "grpc.request.2.value", "messages over the limit must not be tagged")
+	case grpc_ctxtags.StreamFieldsAccumulated:
+		assert.Equal(s.T(), []interface{}{"a"}, allTags[0]["grpc.request.value"], "the first value should be tagged")
+		assert.Equal(s.T(), []interface{}{"a", "b"}, allTags[2]["grpc.request.value"], "repeated values must be tagged once")
+		assert.NotContains(s.T(), allTags[2], "grpc.request.value.truncated", "the values must not be truncated under the limit")
+		assert.Equal(s.T(), []interface{}{"a", "b"}, last["grpc.request.value"], "values over the limit must not be tagged")
+		assert.Equal(s.T(), true, last["grpc.request.value.truncated"], "the values should be marked as truncated")
+	}
+}
+
+func (s *StreamedRequestsTaggingSuite) TestPingList_TagsSingleRequest() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	resp, err := stream.Recv()
+	require.NoError(s.T(), err, "reading stream should not fail")
+
+	tags := tagsFromJson(s.T(), resp.Value)
+	assert.Equal(s.T(), "something", tags["grpc.request.value"], "server-streams should be tagged as usual")
+}
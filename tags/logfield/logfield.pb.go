@@ -0,0 +1,57 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: github.com/grpc-ecosystem/go-grpc-middleware/tags/logfield/logfield.proto
+
+// The `log_field` field option selects the fields that grpc_ctxtags extracts into tags.
+
+package grpc_logfield
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+var E_LogField = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.FieldOptions)(nil),
+	ExtensionType: (*string)(nil),
+	Field:         50510,
+	Name:          "grpc_middleware.logfield.log_field",
+	Tag:           "bytes,50510,opt,name=log_field",
+	Filename:      "github.com/grpc-ecosystem/go-grpc-middleware/tags/logfield/logfield.proto",
+}
+
+func init() {
+	proto.RegisterExtension(E_LogField)
+}
+
+func init() {
+	proto.RegisterFile("github.com/grpc-ecosystem/go-grpc-middleware/tags/logfield/logfield.proto", fileDescriptor_a94cff1d9a6de312)
+}
+
+var fileDescriptor_a94cff1d9a6de312 = []byte{
+	// 183 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xf2, 0x4c, 0xcf, 0x2c, 0xc9,
+	0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0x2f, 0x2a, 0x48, 0xd6, 0x4d, 0x4d, 0xce, 0x2f,
+	0xae, 0x2c, 0x2e, 0x49, 0xcd, 0xd5, 0x4f, 0xcf, 0xd7, 0x05, 0x8b, 0xe4, 0x66, 0xa6, 0xa4, 0xe4,
+	0xa4, 0x96, 0x27, 0x16, 0xa5, 0xea, 0x97, 0x24, 0xa6, 0x17, 0xeb, 0xe7, 0xe4, 0xa7, 0xa7, 0x65,
+	0xa6, 0xe6, 0xa4, 0xc0, 0x19, 0x7a, 0x05, 0x45, 0xf9, 0x25, 0xf9, 0x42, 0x12, 0x20, 0xd5, 0xf1,
+	0x08, 0xd5, 0x7a, 0x30, 0x79, 0x29, 0x85, 0xf4, 0xfc, 0xfc, 0xf4, 0x9c, 0x54, 0x7d, 0xb0, 0xba,
+	0xa4, 0xd2, 0x34, 0xfd, 0x94, 0xd4, 0xe2, 0xe4, 0xa2, 0xcc, 0x82, 0x92, 0xfc, 0x22, 0x88, 0x5e,
+	0x2b, 0x1b, 0x2e, 0xce, 0x9c, 0xfc, 0xf4, 0x78, 0xb0, 0x72, 0x21, 0x59, 0x3d, 0x88, 0x7a, 0x3d,
+	0x98, 0x7a, 0x3d, 0x37, 0x90, 0xb8, 0x7f, 0x41, 0x49, 0x66, 0x7e, 0x5e, 0xb1, 0xc4, 0xb9, 0x2e,
+	0x66, 0x05, 0x46, 0x0d, 0xce, 0x20, 0x8e, 0x9c, 0xfc, 0x74, 0xb0, 0x84, 0x93, 0x57, 0x94, 0x07,
+	0xf9, 0xde, 0xb0, 0x06, 0x3b, 0x1b, 0xc6, 0x4b, 0x62, 0x03, 0x5b, 0x6a, 0x0c, 0x18, 0x00, 0x45,
+	0x4d, 0x92, 0xcc, 0x19, 0x01, 0x00, 0x00,
+}
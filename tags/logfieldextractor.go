@@ -0,0 +1,242 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_ctxtags
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/protobuf/descriptor"
+	"github.com/golang/protobuf/proto"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags/logfield"
+)
+
+// ProtoOptionRequestFieldExtractor is a function that relies on protobuf reflection to export log fields from requests.
+// The exported fields are the ones annotated with the `(grpc_middleware.logfield.log_field)` option of
+// `github.com/grpc-ecosystem/go-grpc-middleware/tags/logfield/logfield.proto`, which sets the name of their tag:
+//
+//	message CreateUserRequest {
+//	   string user_id = 1 [(grpc_middleware.logfield.log_field) = "user_id"];
+//	   repeated string roles = 2 [(grpc_middleware.logfield.log_field) = "roles"];
+//	   Address address = 3; // the annotated fields of Address are exported too.
+//	}
+//
+// Fields of nested messages, including messages of oneofs, are exported as if they were fields of the request.
+// Repeated fields and maps are exported as is, enums as their names, and `google.protobuf.Timestamp` and
+// `google.protobuf.Duration` as time.Time and time.Duration. The fields to export are resolved once per message
+// type, so the cost of each request is the one of reading the annotated fields.
+func ProtoOptionRequestFieldExtractor(fullMethod string, req interface{}) map[string]interface{} {
+	return extractLogFields(req)
+}
+
+// ProtoOptionResponseFieldExtractor is a function that relies on protobuf reflection to export log fields from responses,
+// in the same way as ProtoOptionRequestFieldExtractor does for requests.
+func ProtoOptionResponseFieldExtractor(fullMethod string, resp interface{}) map[string]interface{} {
+	return extractLogFields(resp)
+}
+
+func extractLogFields(msg interface{}) map[string]interface{} {
+	v := reflect.ValueOf(msg)
+	// Only deal with pointers to structs.
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	plan := logFieldPlanFor(v.Type())
+	if len(plan.fields) == 0 {
+		return nil
+	}
+	retMap := make(map[string]interface{})
+	plan.extract(v.Elem(), retMap)
+	if len(retMap) == 0 {
+		return nil
+	}
+	return retMap
+}
+
+type logFieldKind int
+
+const (
+	logFieldValue logFieldKind = iota
+	logFieldEnum
+	logFieldTimestamp
+	logFieldDuration
+	// logFieldNested is a message field without the option, whose own fields are looked at.
+	logFieldNested
+)
+
+// logFieldPlan lists the fields to look at in a message type.
+type logFieldPlan struct {
+	fields []logFieldStep
+}
+
+type logFieldStep struct {
+	tag      string
+	kind     logFieldKind
+	repeated bool
+	// index is the index of the field in the struct, or of the oneof holding it if oneofType is set.
+	index     int
+	oneofType reflect.Type
+	nested    *logFieldPlan
+}
+
+var logFieldPlans = struct {
+	sync.RWMutex
+	plans map[reflect.Type]*logFieldPlan
+}{plans: make(map[reflect.Type]*logFieldPlan)}
+
+func logFieldPlanFor(t reflect.Type) *logFieldPlan {
+	logFieldPlans.RLock()
+	plan, ok := logFieldPlans.plans[t]
+	logFieldPlans.RUnlock()
+	if ok {
+		return plan
+	}
+	logFieldPlans.Lock()
+	defer logFieldPlans.Unlock()
+	return buildLogFieldPlanLocked(t)
+}
+
+// buildLogFieldPlanLocked builds the plan of a pointer to message type. The plan is cached before its fields are
+// resolved, so that recursive message types refer to their own plan.
+func buildLogFieldPlanLocked(t reflect.Type) *logFieldPlan {
+	if plan, ok := logFieldPlans.plans[t]; ok {
+		return plan
+	}
+	plan := &logFieldPlan{}
+	logFieldPlans.plans[t] = plan
+	msg, ok := reflect.Zero(t).Interface().(descriptor.Message)
+	if !ok || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return plan
+	}
+	_, md := descriptor.ForMessage(msg)
+	props := proto.GetProperties(t.Elem())
+	fieldIndexes := make(map[string]int)
+	for i, p := range props.Prop {
+		if p.Tag > 0 {
+			fieldIndexes[p.OrigName] = i
+		}
+	}
+	for _, field := range md.GetField() {
+		step := logFieldStep{
+			tag:      logFieldTag(field),
+			repeated: field.GetLabel() == descpb.FieldDescriptorProto_LABEL_REPEATED,
+		}
+		var goType reflect.Type
+		if oneof, ok := props.OneofTypes[field.GetName()]; ok {
+			step.index = oneof.Field
+			step.oneofType = oneof.Type
+			goType = oneof.Type.Elem().Field(0).Type
+		} else if i, ok := fieldIndexes[field.GetName()]; ok {
+			step.index = i
+			goType = t.Elem().Field(i).Type
+		} else {
+			continue
+		}
+		isMessage := field.GetType() == descpb.FieldDescriptorProto_TYPE_MESSAGE
+		switch {
+		case step.tag != "" && field.GetType() == descpb.FieldDescriptorProto_TYPE_ENUM:
+			step.kind = logFieldEnum
+		case step.tag != "" && isMessage && field.GetTypeName() == ".google.protobuf.Timestamp":
+			step.kind = logFieldTimestamp
+		case step.tag != "" && isMessage && field.GetTypeName() == ".google.protobuf.Duration":
+			step.kind = logFieldDuration
+		case step.tag != "":
+			step.kind = logFieldValue
+		case isMessage && !step.repeated && goType.Kind() == reflect.Ptr:
+			step.kind = logFieldNested
+			step.nested = buildLogFieldPlanLocked(goType)
+		default:
+			continue
+		}
+		plan.fields = append(plan.fields, step)
+	}
+	return plan
+}
+
+func logFieldTag(field *descpb.FieldDescriptorProto) string {
+	if field.GetOptions() == nil {
+		return ""
+	}
+	ext, err := proto.GetExtension(field.GetOptions(), grpc_logfield.E_LogField)
+	if err != nil {
+		return ""
+	}
+	if tag, ok := ext.(*string); ok && tag != nil {
+		return *tag
+	}
+	return ""
+}
+
+func (p *logFieldPlan) extract(v reflect.Value, existingMap map[string]interface{}) {
+	for _, step := range p.fields {
+		field := v.Field(step.index)
+		if step.oneofType != nil {
+			if field.IsNil() || field.Elem().Type() != step.oneofType {
+				continue
+			}
+			field = field.Elem().Elem().Field(0)
+		}
+		if step.kind == logFieldNested {
+			if !field.IsNil() {
+				step.nested.extract(field.Elem(), existingMap)
+			}
+			continue
+		}
+		if value, ok := step.value(field); ok {
+			existingMap[step.tag] = value
+		}
+	}
+}
+
+func (s *logFieldStep) value(field reflect.Value) (interface{}, bool) {
+	if !s.repeated {
+		if field.Kind() == reflect.Ptr && field.IsNil() {
+			return nil, false
+		}
+		return s.convert(field.Interface())
+	}
+	// Repeated fields and maps.
+	if field.Len() == 0 {
+		return nil, false
+	}
+	if s.kind == logFieldValue {
+		return field.Interface(), true
+	}
+	values := make([]interface{}, 0, field.Len())
+	for i := 0; i < field.Len(); i++ {
+		if value, ok := s.convert(field.Index(i).Interface()); ok {
+			values = append(values, value)
+		}
+	}
+	return values, true
+}
+
+func (s *logFieldStep) convert(value interface{}) (interface{}, bool) {
+	switch s.kind {
+	case logFieldEnum:
+		if e, ok := value.(fmt.Stringer); ok {
+			return e.String(), true
+		}
+		return value, true
+	case logFieldTimestamp:
+		if ts, ok := value.(*timestamp.Timestamp); ok && ts != nil {
+			t, err := ptypes.Timestamp(ts)
+			return t, err == nil
+		}
+		return nil, false
+	case logFieldDuration:
+		if d, ok := value.(*duration.Duration); ok && d != nil {
+			t, err := ptypes.Duration(d)
+			return t, err == nil
+		}
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_ctxtags_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	pb_logfieldtestproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/logfieldtestproto"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtoOptionFieldExtractor_OrderRequest(t *testing.T) {
+	deliverAt := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	deliverAtProto, _ := ptypes.TimestampProto(deliverAt)
+	req := &pb_logfieldtestproto.OrderRequest{
+		User:      &pb_logfieldtestproto.User{Id: "u-1", Name: "not logged"}, // logfield is user_id
+		Tags:      []string{"tagone", "tagtwo"},
+		Labels:    map[string]string{"env": "prod"},
+		Payment:   &pb_logfieldtestproto.OrderRequest_CardId{CardId: "c-1"},
+		DeliverAt: deliverAtProto,
+		Ttl:       ptypes.DurationProto(3 * time.Second),
+		Status:    pb_logfieldtestproto.Status_PENDING,
+		Amount:    1337,
+		Items:     []*pb_logfieldtestproto.Item{{Sku: "not logged"}},
+		Secret:    "hunter2",
+		Node:      &pb_logfieldtestproto.Node{Name: "root", Child: &pb_logfieldtestproto.Node{Name: "leaf"}},
+		History:   []*timestamp.Timestamp{deliverAtProto},
+	}
+	valMap := grpc_ctxtags.ProtoOptionRequestFieldExtractor("", req)
+	assert.Equal(t, map[string]interface{}{
+		"user_id":    "u-1",
+		"tags":       []string{"tagone", "tagtwo"},
+		"labels":     map[string]string{"env": "prod"},
+		"card_id":    "c-1",
+		"deliver_at": deliverAt,
+		"ttl":        3 * time.Second,
+		"status":     "PENDING",
+		"amount":     int64(1337),
+		"node_name":  "leaf",
+		"history":    []interface{}{deliverAt},
+	}, valMap)
+}
+
+func TestProtoOptionFieldExtractor_OneOfMessage(t *testing.T) {
+	req := &pb_logfieldtestproto.OrderRequest{
+		Payment: &pb_logfieldtestproto.OrderRequest_Payer{Payer: &pb_logfieldtestproto.User{Id: "payer-1"}},
+	}
+	valMap := grpc_ctxtags.ProtoOptionRequestFieldExtractor("", req)
+	assert.Equal(t, "payer-1", valMap["user_id"], "the fields of messages in oneofs should be extracted")
+	assert.NotContains(t, valMap, "card_id", "fields of oneofs that aren't set must not be extracted")
+	assert.NotContains(t, valMap, "deliver_at", "unset messages must not be extracted")
+	assert.NotContains(t, valMap, "tags", "empty repeated fields must not be extracted")
+}
+
+func TestProtoOptionFieldExtractor_NoAnnotatedFields(t *testing.T) {
+	assert.Nil(t, grpc_ctxtags.ProtoOptionRequestFieldExtractor("", &pb_testproto.PingRequest{Value: "something"}))
+	assert.Nil(t, grpc_ctxtags.ProtoOptionResponseFieldExtractor("", (*pb_logfieldtestproto.User)(nil)))
+	assert.Nil(t, grpc_ctxtags.ProtoOptionResponseFieldExtractor("", "not a message"))
+}
+
+func TestProtoOptionFieldExtractor_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			valMap := grpc_ctxtags.ProtoOptionResponseFieldExtractor("", &pb_logfieldtestproto.Item{Sku: "sku-1"})
+			require.Equal(t, map[string]interface{}{"item_sku": "sku-1"}, valMap)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_ctxtags
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RedactedValue replaces the values of redacted metadata keys.
+const RedactedValue = "[redacted]"
+
+// MetadataExtractorFunc is a user-provided function that extracts tags from the incoming metadata of a request.
+// It is called from tags middleware on arrival of every request, and the keys it returns are prefixed with
+// `grpc.request.metadata.`. If there are no tags, you should return a nil.
+type MetadataExtractorFunc func(fullMethod string, md metadata.MD) map[string]interface{}
+
+// MetadataRule selects the incoming metadata keys extracted by `MetadataKeysExtractor`.
+type MetadataRule struct {
+	// Key is the lower-case metadata key to extract, or the prefix of the keys to extract if Prefix is set.
+	Key    string
+	Prefix bool
+	// Rename is the name of the tag, instead of the metadata key. For prefix rules it replaces the prefix,
+	// e.g. the rule `{Key: "x-b3-", Prefix: true, Rename: "b3."}` tags `x-b3-traceid` as `b3.traceid`.
+	Rename string
+	// Redact replaces the values with `RedactedValue`, to record that the key was sent without leaking it.
+	Redact bool
+}
+
+// MetadataKeysExtractor returns a MetadataExtractorFunc that extracts the metadata keys matching the rules,
+// e.g. `MetadataKeysExtractor(MetadataRule{Key: "user-agent"})` tags `grpc.request.metadata.user-agent`.
+//
+// The first rule matching a key is used. Keys with a single value are tagged with a string, and keys with
+// multiple values with a []string. Binary values (of `-bin` keys) are base64 encoded.
+func MetadataKeysExtractor(rules ...MetadataRule) MetadataExtractorFunc {
+	return func(fullMethod string, md metadata.MD) map[string]interface{} {
+		var ret map[string]interface{}
+		for key, values := range md {
+			rule, ok := matchMetadataRule(rules, key)
+			if !ok || len(values) == 0 {
+				continue
+			}
+			if ret == nil {
+				ret = make(map[string]interface{})
+			}
+			ret[rule.tagName(key)] = metadataTagValue(rule, key, values)
+		}
+		return ret
+	}
+}
+
+func matchMetadataRule(rules []MetadataRule, key string) (MetadataRule, bool) {
+	for _, r := range rules {
+		if r.Prefix && strings.HasPrefix(key, r.Key) || !r.Prefix && key == r.Key {
+			return r, true
+		}
+	}
+	return MetadataRule{}, false
+}
+
+func (r MetadataRule) tagName(key string) string {
+	if r.Rename == "" {
+		return key
+	}
+	if r.Prefix {
+		return r.Rename + strings.TrimPrefix(key, r.Key)
+	}
+	return r.Rename
+}
+
+func metadataTagValue(r MetadataRule, key string, values []string) interface{} {
+	if r.Redact {
+		return RedactedValue
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		if strings.HasSuffix(key, "-bin") {
+			v = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		out[i] = v
+	}
+	if len(out) == 1 {
+		return out[0]
+	}
+	return out
+}
+
+func setMetadataTags(ctx context.Context, t Tags, f MetadataExtractorFunc, fullMethodName string) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return
+	}
+	for k, v := range f(fullMethodName, md) {
+		t.Set("grpc.request.metadata."+k, v)
+	}
+}
+
+// setCallInfoTags tags the standard facts about a request: its authority, content type, compression and deadline.
+func setCallInfoTags(ctx context.Context, t Tags) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(":authority"); len(v) > 0 {
+		t.Set("grpc.request.authority", v[0])
+	}
+	if v := md.Get("content-type"); len(v) > 0 {
+		t.Set("grpc.request.content_type", v[0])
+	}
+	// The transport stream of grpc-go knows the compression of the request, which isn't part of the metadata.
+	if s, ok := grpc.ServerTransportStreamFromContext(ctx).(interface{ RecvCompress() string }); ok && s.RecvCompress() != "" {
+		t.Set("grpc.request.compression", s.RecvCompress())
+	}
+	if d, ok := ctx.Deadline(); ok {
+		t.Set("grpc.request.deadline", d.Format(time.RFC3339))
+	}
+}
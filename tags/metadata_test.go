@@ -0,0 +1,102 @@
+package grpc_ctxtags_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMetadataKeysExtractor(t *testing.T) {
+	f := grpc_ctxtags.MetadataKeysExtractor(
+		grpc_ctxtags.MetadataRule{Key: "user-agent"},
+		grpc_ctxtags.MetadataRule{Key: "x-request-id", Rename: "request_id"},
+		grpc_ctxtags.MetadataRule{Key: "authorization", Redact: true},
+		grpc_ctxtags.MetadataRule{Key: "x-b3-", Prefix: true, Rename: "b3."},
+		grpc_ctxtags.MetadataRule{Key: "x-multi"},
+		grpc_ctxtags.MetadataRule{Key: "x-raw-bin"},
+	)
+	md := metadata.Pairs(
+		"user-agent", "grpc-go/1.29",
+		"x-request-id", "1234",
+		"authorization", "bearer secret",
+		"x-b3-traceid", "abc",
+		"x-b3-spanid", "def",
+		"x-multi", "a",
+		"x-multi", "b",
+		"x-raw-bin", "\x00\x01",
+		"x-ignored", "ignored",
+	)
+	assert.Equal(t, map[string]interface{}{
+		"user-agent":    "grpc-go/1.29",
+		"request_id":    "1234",
+		"authorization": grpc_ctxtags.RedactedValue,
+		"b3.traceid":    "abc",
+		"b3.spanid":     "def",
+		"x-multi":       []string{"a", "b"},
+		"x-raw-bin":     "AAE=",
+	}, f("/mwitkow.testproto.TestService/Ping", md))
+	assert.Nil(t, f("/mwitkow.testproto.TestService/Ping", metadata.Pairs("x-ignored", "ignored")), "no match must return nil")
+}
+
+func TestMetadataTaggingSuite(t *testing.T) {
+	opts := []grpc_ctxtags.Option{
+		grpc_ctxtags.WithMetadataExtractor(grpc_ctxtags.MetadataKeysExtractor(
+			grpc_ctxtags.MetadataRule{Key: "x-tenant", Rename: "tenant"},
+			grpc_ctxtags.MetadataRule{Key: "x-api-key", Redact: true},
+		)),
+		grpc_ctxtags.WithCallInfoTags(),
+	}
+	s := &MetadataTaggingSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &tagPingBack{&grpc_testing.TestPingService{T: t}},
+			ServerOpts: []grpc.ServerOption{
+				grpc.StreamInterceptor(grpc_ctxtags.StreamServerInterceptor(opts...)),
+				grpc.UnaryInterceptor(grpc_ctxtags.UnaryServerInterceptor(opts...)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type MetadataTaggingSuite struct {
+	*grpc_testing.InterceptorTestSuite
+}
+
+func (s *MetadataTaggingSuite) TestPing_TagsMetadataAndCallInfo() {
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-tenant", "acme", "x-api-key", "secret")
+	resp, err := s.Client.Ping(ctx, goodPing, grpc.UseCompressor(gzip.Name))
+	require.NoError(s.T(), err, "must not be an error on a successful call")
+
+	tags := tagsFromJson(s.T(), resp.Value)
+	assert.Equal(s.T(), "acme", tags["grpc.request.metadata.tenant"], "the tags should contain the renamed metadata key")
+	assert.Equal(s.T(), grpc_ctxtags.RedactedValue, tags["grpc.request.metadata.x-api-key"], "the tags should contain the redacted metadata key")
+	assert.NotEmpty(s.T(), tags["grpc.request.authority"], "the tags should contain the authority")
+	assert.Equal(s.T(), "application/grpc", tags["grpc.request.content_type"], "the tags should contain the content type")
+	assert.Equal(s.T(), "gzip", tags["grpc.request.compression"], "the tags should contain the compression")
+	assert.Equal(s.T(), deadline.Format(time.RFC3339), tags["grpc.request.deadline"], "the tags should contain the deadline")
+}
+
+func (s *MetadataTaggingSuite) TestPingList_TagsMetadata() {
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(), "x-tenant", "acme")
+	stream, err := s.Client.PingList(ctx, goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	resp, err := stream.Recv()
+	require.NoError(s.T(), err, "reading stream should not fail")
+
+	tags := tagsFromJson(s.T(), resp.Value)
+	assert.Equal(s.T(), "acme", tags["grpc.request.metadata.tenant"], "the tags should contain the renamed metadata key")
+	assert.NotContains(s.T(), tags, "grpc.request.metadata.x-api-key", "keys that weren't sent must not be tagged")
+	assert.NotContains(s.T(), tags, "grpc.request.compression", "uncompressed requests must not be tagged with a compression")
+}
@@ -6,12 +6,22 @@ package grpc_ctxtags
 var (
 	defaultOptions = &options{
 		requestFieldsFunc: nil,
+		newTags:           NewTags,
+		propagationPrefix: DefaultPropagationPrefix,
 	}
 )
 
 type options struct {
 	requestFieldsFunc        RequestFieldExtractorFunc
 	requestFieldsFromInitial bool
+	streamFieldsMode         StreamFieldsMode
+	streamFieldsLimit        uint
+	responseFieldsFunc       ResponseFieldExtractorFunc
+	newTags                  func() Tags
+	propagatedTags           []string
+	propagationPrefix        string
+	metadataFunc             MetadataExtractorFunc
+	callInfoTags             bool
 }
 
 func evaluateOptions(opts []Option) *options {
@@ -30,6 +40,8 @@ type Option func(*options)
 func WithFieldExtractor(f RequestFieldExtractorFunc) Option {
 	return func(o *options) {
 		o.requestFieldsFunc = f
+		o.requestFieldsFromInitial = false
+		o.streamFieldsMode = 0
 	}
 }
 
@@ -40,5 +52,97 @@ func WithFieldExtractorForInitialReq(f RequestFieldExtractorFunc) Option {
 	return func(o *options) {
 		o.requestFieldsFunc = f
 		o.requestFieldsFromInitial = true
+		o.streamFieldsMode = 0
+	}
+}
+
+// StreamFieldsMode selects how the fields of every message of client-streams and bidirectional-streams are tagged.
+type StreamFieldsMode int
+
+const (
+	// StreamFieldsIndexed tags the fields of the n-th message, starting at 0, as `grpc.request.<n>.<field_name>`.
+	// Only the first messages, up to the limit, are tagged.
+	StreamFieldsIndexed StreamFieldsMode = iota + 1
+	// StreamFieldsAccumulated tags the distinct values of each field, in the order they are received, as a
+	// []interface{} in `grpc.request.<field_name>`. Once a field has as many distinct values as the limit, new values
+	// are dropped and `grpc.request.<field_name>.truncated` is set.
+	StreamFieldsAccumulated
+)
+
+// DefaultStreamFieldsLimit is the number of messages or distinct values tagged when no limit is set.
+const DefaultStreamFieldsLimit = 10
+
+// WithFieldExtractorForStreamedReqs customizes the function for extracting log fields from protobuf messages,
+// for all unary and streaming methods. For client-streams and bidirectional-streams, the tags are extracted from
+// every message from the client as set by mode, with at most limit messages or distinct values per field so that
+// long-lived streams don't grow their tags forever (DefaultStreamFieldsLimit if 0). The number of messages received
+// by the server, or sent by the client, is tagged in `grpc.request.messages`.
+func WithFieldExtractorForStreamedReqs(f RequestFieldExtractorFunc, mode StreamFieldsMode, limit uint) Option {
+	return func(o *options) {
+		o.requestFieldsFunc = f
+		o.requestFieldsFromInitial = false
+		o.streamFieldsMode = mode
+		o.streamFieldsLimit = limit
+		if limit == 0 {
+			o.streamFieldsLimit = DefaultStreamFieldsLimit
+		}
+	}
+}
+
+// WithResponseFieldExtractor customizes the function for extracting log fields from protobuf messages sent
+// back to the client, as `grpc.response.<field_name>` tags. The response of unary methods is extracted once the handler
+// returns successfully. For streaming methods the fields are extracted from every message sent, so the tags hold the
+// values of the last one.
+func WithResponseFieldExtractor(f ResponseFieldExtractorFunc) Option {
+	return func(o *options) {
+		o.responseFieldsFunc = f
+	}
+}
+
+// WithThreadSafeTags controls whether the Tags put in the context are thread safe, which is the default.
+//
+// Only disable it if no handler or middleware uses the Tags from more than one goroutine at a time.
+func WithThreadSafeTags(enabled bool) Option {
+	return func(o *options) {
+		if enabled {
+			o.newTags = NewTags
+		} else {
+			o.newTags = NewUnsynchronizedTags
+		}
+	}
+}
+
+// WithPropagatedTags sets the tags that flow through calls, e.g. a tenant or request ID.
+//
+// The client interceptors copy these tags from the caller's context into the outgoing metadata, and the server
+// interceptors import them from the incoming metadata as string tags. Tags that aren't listed are neither sent
+// nor imported, so a client can't set arbitrary tags on the server.
+func WithPropagatedTags(keys ...string) Option {
+	return func(o *options) {
+		o.propagatedTags = keys
+	}
+}
+
+// WithPropagationPrefix customizes the prefix of the metadata keys carrying propagated tags, which is
+// `DefaultPropagationPrefix` by default. The same prefix must be used by clients and servers.
+func WithPropagationPrefix(prefix string) Option {
+	return func(o *options) {
+		o.propagationPrefix = prefix
+	}
+}
+
+// WithMetadataExtractor customizes the function for extracting tags from the incoming metadata of requests,
+// for all unary and streaming methods. See `MetadataKeysExtractor`.
+func WithMetadataExtractor(f MetadataExtractorFunc) Option {
+	return func(o *options) {
+		o.metadataFunc = f
+	}
+}
+
+// WithCallInfoTags tags the authority (`grpc.request.authority`), content type (`grpc.request.content_type`),
+// compression (`grpc.request.compression`) and deadline (`grpc.request.deadline`, RFC3339) of requests.
+func WithCallInfoTags() Option {
+	return func(o *options) {
+		o.callInfoTags = true
 	}
 }
@@ -0,0 +1,60 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_ctxtags
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+)
+
+// DefaultPropagationPrefix is the prefix of the metadata keys that carry propagated tags.
+const DefaultPropagationPrefix = "x-tag-"
+
+// propagationKey returns the metadata key carrying a tag. Metadata keys are lower case.
+func propagationKey(o *options, tagKey string) string {
+	return strings.ToLower(o.propagationPrefix + tagKey)
+}
+
+// propagateTags adds the propagated tags of the context to its outgoing metadata. Values already set in
+// the outgoing metadata by the caller are kept.
+func propagateTags(ctx context.Context, o *options) context.Context {
+	if len(o.propagatedTags) == 0 {
+		return ctx
+	}
+	tags := Extract(ctx)
+	var md metautils.NiceMD
+	for _, key := range o.propagatedTags {
+		value, ok := Get(tags, key)
+		if !ok {
+			continue
+		}
+		if md == nil {
+			md = metautils.ExtractOutgoing(ctx).Clone()
+		}
+		mdKey := propagationKey(o, key)
+		if md.Get(mdKey) == "" {
+			md.Set(mdKey, fmt.Sprint(value))
+		}
+	}
+	if md == nil {
+		return ctx
+	}
+	return md.ToOutgoing(ctx)
+}
+
+// importPropagatedTags sets the propagated tags found in the incoming metadata of the context as string tags.
+func importPropagatedTags(ctx context.Context, o *options, tags Tags) {
+	if len(o.propagatedTags) == 0 {
+		return
+	}
+	md := metautils.ExtractIncoming(ctx)
+	for _, key := range o.propagatedTags {
+		if value := md.Get(propagationKey(o, key)); value != "" {
+			tags.Set(key, value)
+		}
+	}
+}
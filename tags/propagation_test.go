@@ -0,0 +1,89 @@
+package grpc_ctxtags_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPropagationSuite(t *testing.T) {
+	opts := []grpc_ctxtags.Option{
+		grpc_ctxtags.WithPropagatedTags("tenant.id", "request_id"),
+	}
+	s := &PropagationSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &tagPingBack{&grpc_testing.TestPingService{T: t}},
+			ServerOpts: []grpc.ServerOption{
+				grpc.StreamInterceptor(grpc_ctxtags.StreamServerInterceptor(opts...)),
+				grpc.UnaryInterceptor(grpc_ctxtags.UnaryServerInterceptor(opts...)),
+			},
+			ClientOpts: []grpc.DialOption{
+				grpc.WithUnaryInterceptor(grpc_ctxtags.UnaryClientInterceptor(opts...)),
+				grpc.WithStreamInterceptor(grpc_ctxtags.StreamClientInterceptor(opts...)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type PropagationSuite struct {
+	*grpc_testing.InterceptorTestSuite
+}
+
+func (s *PropagationSuite) callerCtx() context.Context {
+	tags := grpc_ctxtags.NewTags().Set("tenant.id", "acme").Set("request_id", 42).Set("secret", "hunter2")
+	return grpc_ctxtags.SetInContext(s.SimpleCtx(), tags)
+}
+
+func (s *PropagationSuite) TestPing_PropagatesAllowedTags() {
+	resp, err := s.Client.Ping(s.callerCtx(), goodPing)
+	require.NoError(s.T(), err, "must not be an error on a successful call")
+
+	tags := tagsFromJson(s.T(), resp.Value)
+	assert.Equal(s.T(), "acme", tags["tenant.id"], "the tags should contain the propagated tenant")
+	assert.Equal(s.T(), "42", tags["request_id"], "the tags should contain the propagated request ID as a string")
+	assert.NotContains(s.T(), tags, "secret", "tags that aren't allowed must not be propagated")
+}
+
+func (s *PropagationSuite) TestPingList_PropagatesAllowedTags() {
+	stream, err := s.Client.PingList(s.callerCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+
+		tags := tagsFromJson(s.T(), resp.Value)
+		assert.Equal(s.T(), "acme", tags["tenant.id"], "the tags should contain the propagated tenant")
+		assert.NotContains(s.T(), tags, "secret", "tags that aren't allowed must not be propagated")
+	}
+}
+
+func (s *PropagationSuite) TestPing_KeepsExplicitMetadata() {
+	ctx := metadata.AppendToOutgoingContext(s.callerCtx(), grpc_ctxtags.DefaultPropagationPrefix+"tenant.id", "explicit")
+	resp, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "must not be an error on a successful call")
+
+	tags := tagsFromJson(s.T(), resp.Value)
+	assert.Equal(s.T(), "explicit", tags["tenant.id"], "metadata set by the caller must not be overwritten")
+}
+
+func (s *PropagationSuite) TestPing_IgnoresMetadataNotAllowed() {
+	ctx := metadata.AppendToOutgoingContext(s.SimpleCtx(), grpc_ctxtags.DefaultPropagationPrefix+"secret", "forged")
+	resp, err := s.Client.Ping(ctx, goodPing)
+	require.NoError(s.T(), err, "must not be an error on a successful call")
+
+	tags := tagsFromJson(s.T(), resp.Value)
+	assert.NotContains(s.T(), tags, "secret", "the server must only import allowed tags")
+	assert.NotContains(s.T(), tags, "tenant.id", "tags missing from the caller must not be set")
+}
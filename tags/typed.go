@@ -0,0 +1,84 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_ctxtags
+
+import (
+	"math"
+	"time"
+)
+
+// SetString sets a string tag.
+func SetString(t Tags, key string, value string) Tags {
+	return t.Set(key, value)
+}
+
+// SetInt64 sets an int64 tag.
+func SetInt64(t Tags, key string, value int64) Tags {
+	return t.Set(key, value)
+}
+
+// SetBool sets a bool tag.
+func SetBool(t Tags, key string, value bool) Tags {
+	return t.Set(key, value)
+}
+
+// SetDuration sets a time.Duration tag.
+func SetDuration(t Tags, key string, value time.Duration) Tags {
+	return t.Set(key, value)
+}
+
+// GetString returns the value of a string tag. It returns false if the tag doesn't exist or isn't a string.
+func GetString(t Tags, key string) (string, bool) {
+	v, _ := Get(t, key)
+	s, ok := v.(string)
+	return s, ok
+}
+
+// GetInt64 returns the value of an integer tag. It returns false if the tag doesn't exist, isn't an integer
+// or doesn't fit in an int64.
+func GetInt64(t Tags, key string) (int64, bool) {
+	v, _ := Get(t, key)
+	switch i := v.(type) {
+	case int:
+		return int64(i), true
+	case int8:
+		return int64(i), true
+	case int16:
+		return int64(i), true
+	case int32:
+		return int64(i), true
+	case int64:
+		return i, true
+	case uint:
+		if uint64(i) <= math.MaxInt64 {
+			return int64(i), true
+		}
+	case uint8:
+		return int64(i), true
+	case uint16:
+		return int64(i), true
+	case uint32:
+		return int64(i), true
+	case uint64:
+		if i <= math.MaxInt64 {
+			return int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// GetBool returns the value of a bool tag. It returns false if the tag doesn't exist or isn't a bool.
+func GetBool(t Tags, key string) (bool, bool) {
+	v, _ := Get(t, key)
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// GetDuration returns the value of a time.Duration tag. It returns false if the tag doesn't exist or isn't
+// a time.Duration.
+func GetDuration(t Tags, key string) (time.Duration, bool) {
+	v, _ := Get(t, key)
+	d, ok := v.(time.Duration)
+	return d, ok
+}
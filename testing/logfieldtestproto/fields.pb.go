@@ -0,0 +1,407 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: fields.proto
+
+// This file is used for testing discovery of log fields from requests using protobuf reflection and the log_field option.
+
+package mwitkow_logfieldtestproto
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	_ "github.com/grpc-ecosystem/go-grpc-middleware/tags/logfield"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Status int32
+
+const (
+	Status_UNKNOWN Status = 0
+	Status_PENDING Status = 1
+)
+
+var Status_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "PENDING",
+}
+
+var Status_value = map[string]int32{
+	"UNKNOWN": 0,
+	"PENDING": 1,
+}
+
+func (x Status) String() string {
+	return proto.EnumName(Status_name, int32(x))
+}
+
+func (Status) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_d39ad626ec0e575e, []int{0}
+}
+
+type User struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *User) Reset()         { *m = User{} }
+func (m *User) String() string { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()    {}
+func (*User) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d39ad626ec0e575e, []int{0}
+}
+
+func (m *User) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_User.Unmarshal(m, b)
+}
+func (m *User) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_User.Marshal(b, m, deterministic)
+}
+func (m *User) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_User.Merge(m, src)
+}
+func (m *User) XXX_Size() int {
+	return xxx_messageInfo_User.Size(m)
+}
+func (m *User) XXX_DiscardUnknown() {
+	xxx_messageInfo_User.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_User proto.InternalMessageInfo
+
+func (m *User) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *User) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type Item struct {
+	Sku                  string   `protobuf:"bytes,1,opt,name=sku,proto3" json:"sku,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Item) Reset()         { *m = Item{} }
+func (m *Item) String() string { return proto.CompactTextString(m) }
+func (*Item) ProtoMessage()    {}
+func (*Item) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d39ad626ec0e575e, []int{1}
+}
+
+func (m *Item) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Item.Unmarshal(m, b)
+}
+func (m *Item) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Item.Marshal(b, m, deterministic)
+}
+func (m *Item) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Item.Merge(m, src)
+}
+func (m *Item) XXX_Size() int {
+	return xxx_messageInfo_Item.Size(m)
+}
+func (m *Item) XXX_DiscardUnknown() {
+	xxx_messageInfo_Item.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Item proto.InternalMessageInfo
+
+func (m *Item) GetSku() string {
+	if m != nil {
+		return m.Sku
+	}
+	return ""
+}
+
+type Node struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Child                *Node    `protobuf:"bytes,2,opt,name=child,proto3" json:"child,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Node) Reset()         { *m = Node{} }
+func (m *Node) String() string { return proto.CompactTextString(m) }
+func (*Node) ProtoMessage()    {}
+func (*Node) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d39ad626ec0e575e, []int{2}
+}
+
+func (m *Node) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Node.Unmarshal(m, b)
+}
+func (m *Node) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Node.Marshal(b, m, deterministic)
+}
+func (m *Node) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Node.Merge(m, src)
+}
+func (m *Node) XXX_Size() int {
+	return xxx_messageInfo_Node.Size(m)
+}
+func (m *Node) XXX_DiscardUnknown() {
+	xxx_messageInfo_Node.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Node proto.InternalMessageInfo
+
+func (m *Node) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Node) GetChild() *Node {
+	if m != nil {
+		return m.Child
+	}
+	return nil
+}
+
+type OrderRequest struct {
+	User   *User             `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Tags   []string          `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	Labels map[string]string `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Types that are valid to be assigned to Payment:
+	//	*OrderRequest_CardId
+	//	*OrderRequest_Payer
+	Payment              isOrderRequest_Payment `protobuf_oneof:"payment"`
+	DeliverAt            *timestamp.Timestamp   `protobuf:"bytes,6,opt,name=deliver_at,json=deliverAt,proto3" json:"deliver_at,omitempty"`
+	Ttl                  *duration.Duration     `protobuf:"bytes,7,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Status               Status                 `protobuf:"varint,8,opt,name=status,proto3,enum=mwitkow.logfieldtestproto.Status" json:"status,omitempty"`
+	Amount               int64                  `protobuf:"varint,9,opt,name=amount,proto3" json:"amount,omitempty"`
+	Items                []*Item                `protobuf:"bytes,10,rep,name=items,proto3" json:"items,omitempty"`
+	Secret               string                 `protobuf:"bytes,11,opt,name=secret,proto3" json:"secret,omitempty"`
+	Node                 *Node                  `protobuf:"bytes,12,opt,name=node,proto3" json:"node,omitempty"`
+	History              []*timestamp.Timestamp `protobuf:"bytes,13,rep,name=history,proto3" json:"history,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *OrderRequest) Reset()         { *m = OrderRequest{} }
+func (m *OrderRequest) String() string { return proto.CompactTextString(m) }
+func (*OrderRequest) ProtoMessage()    {}
+func (*OrderRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d39ad626ec0e575e, []int{3}
+}
+
+func (m *OrderRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OrderRequest.Unmarshal(m, b)
+}
+func (m *OrderRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OrderRequest.Marshal(b, m, deterministic)
+}
+func (m *OrderRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OrderRequest.Merge(m, src)
+}
+func (m *OrderRequest) XXX_Size() int {
+	return xxx_messageInfo_OrderRequest.Size(m)
+}
+func (m *OrderRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_OrderRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OrderRequest proto.InternalMessageInfo
+
+func (m *OrderRequest) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func (m *OrderRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *OrderRequest) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+type isOrderRequest_Payment interface {
+	isOrderRequest_Payment()
+}
+
+type OrderRequest_CardId struct {
+	CardId string `protobuf:"bytes,4,opt,name=card_id,json=cardId,proto3,oneof"`
+}
+
+type OrderRequest_Payer struct {
+	Payer *User `protobuf:"bytes,5,opt,name=payer,proto3,oneof"`
+}
+
+func (*OrderRequest_CardId) isOrderRequest_Payment() {}
+
+func (*OrderRequest_Payer) isOrderRequest_Payment() {}
+
+func (m *OrderRequest) GetPayment() isOrderRequest_Payment {
+	if m != nil {
+		return m.Payment
+	}
+	return nil
+}
+
+func (m *OrderRequest) GetCardId() string {
+	if x, ok := m.GetPayment().(*OrderRequest_CardId); ok {
+		return x.CardId
+	}
+	return ""
+}
+
+func (m *OrderRequest) GetPayer() *User {
+	if x, ok := m.GetPayment().(*OrderRequest_Payer); ok {
+		return x.Payer
+	}
+	return nil
+}
+
+func (m *OrderRequest) GetDeliverAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.DeliverAt
+	}
+	return nil
+}
+
+func (m *OrderRequest) GetTtl() *duration.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+func (m *OrderRequest) GetStatus() Status {
+	if m != nil {
+		return m.Status
+	}
+	return Status_UNKNOWN
+}
+
+func (m *OrderRequest) GetAmount() int64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *OrderRequest) GetItems() []*Item {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *OrderRequest) GetSecret() string {
+	if m != nil {
+		return m.Secret
+	}
+	return ""
+}
+
+func (m *OrderRequest) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func (m *OrderRequest) GetHistory() []*timestamp.Timestamp {
+	if m != nil {
+		return m.History
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*OrderRequest) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*OrderRequest_CardId)(nil),
+		(*OrderRequest_Payer)(nil),
+	}
+}
+
+func init() {
+	proto.RegisterEnum("mwitkow.logfieldtestproto.Status", Status_name, Status_value)
+	proto.RegisterType((*User)(nil), "mwitkow.logfieldtestproto.User")
+	proto.RegisterType((*Item)(nil), "mwitkow.logfieldtestproto.Item")
+	proto.RegisterType((*Node)(nil), "mwitkow.logfieldtestproto.Node")
+	proto.RegisterType((*OrderRequest)(nil), "mwitkow.logfieldtestproto.OrderRequest")
+	proto.RegisterMapType((map[string]string)(nil), "mwitkow.logfieldtestproto.OrderRequest.LabelsEntry")
+}
+
+func init() { proto.RegisterFile("fields.proto", fileDescriptor_d39ad626ec0e575e) }
+
+var fileDescriptor_d39ad626ec0e575e = []byte{
+	// 629 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x53, 0xed, 0x4e, 0xdb, 0x3a,
+	0x18, 0xa6, 0x4d, 0x9b, 0xd0, 0xb7, 0x05, 0x21, 0xeb, 0xe8, 0xc8, 0xf4, 0x1c, 0x41, 0xe8, 0x0f,
+	0x54, 0x1d, 0x89, 0x54, 0x82, 0x33, 0xb1, 0xed, 0x1f, 0x15, 0x68, 0x54, 0x9b, 0xca, 0xe4, 0x0d,
+	0xf1, 0xb3, 0x4b, 0x6b, 0x13, 0xac, 0x26, 0x75, 0x67, 0x3b, 0xa0, 0xde, 0xc7, 0x2e, 0x2d, 0x57,
+	0x92, 0x2b, 0x98, 0x6c, 0xa7, 0x1b, 0xda, 0x34, 0xe0, 0x4f, 0xfc, 0x7e, 0x3c, 0xef, 0x93, 0x37,
+	0x4f, 0x1e, 0x43, 0xe7, 0x96, 0xb3, 0x94, 0xaa, 0x68, 0x29, 0x85, 0x16, 0x68, 0x37, 0x7b, 0xe0,
+	0x7a, 0x2e, 0x1e, 0xa2, 0x54, 0x24, 0xb6, 0xa1, 0x99, 0xd2, 0xb6, 0xd5, 0x1d, 0x25, 0x5c, 0xdf,
+	0xe5, 0xd3, 0x68, 0x26, 0xb2, 0x41, 0x22, 0x97, 0xb3, 0x23, 0x36, 0x13, 0x6a, 0xa5, 0x34, 0xcb,
+	0x06, 0x89, 0x38, 0xb2, 0x95, 0x8c, 0x53, 0x9a, 0xb2, 0x87, 0x58, 0xb2, 0x81, 0x8e, 0x13, 0x35,
+	0x58, 0x93, 0xfc, 0x08, 0xdc, 0x5b, 0xba, 0x7b, 0x89, 0x10, 0x49, 0xca, 0x06, 0x36, 0x9b, 0xe6,
+	0xb7, 0x03, 0x9a, 0xcb, 0x58, 0x73, 0xb1, 0xa8, 0xfa, 0xfb, 0xbf, 0xf6, 0x35, 0xcf, 0x98, 0xd2,
+	0x71, 0xb6, 0x74, 0x80, 0xde, 0x29, 0x34, 0xae, 0x15, 0x93, 0xe8, 0x1f, 0xa8, 0x73, 0x8a, 0x6b,
+	0x61, 0xad, 0xdf, 0x1a, 0xb6, 0xcb, 0x02, 0x07, 0xb9, 0x62, 0x72, 0xc2, 0x29, 0xa9, 0x73, 0x8a,
+	0x10, 0x34, 0x16, 0x71, 0xc6, 0x70, 0xdd, 0xb4, 0x89, 0x8d, 0x7b, 0x87, 0xd0, 0x18, 0x69, 0x96,
+	0xa1, 0x3d, 0xf0, 0xd4, 0x3c, 0xaf, 0x26, 0x3b, 0x65, 0x81, 0x37, 0xb9, 0x66, 0xd9, 0x44, 0xcd,
+	0x73, 0x62, 0x1a, 0xbd, 0x2f, 0xd0, 0x18, 0x0b, 0xca, 0xd0, 0x41, 0xc5, 0xe1, 0x80, 0x5b, 0x65,
+	0x81, 0x5b, 0x0b, 0x41, 0xd9, 0xc4, 0x14, 0x1d, 0x25, 0x7a, 0x05, 0xcd, 0xd9, 0x1d, 0x4f, 0xa9,
+	0x7d, 0x4f, 0xfb, 0x78, 0x3f, 0xfa, 0xa3, 0x84, 0x91, 0xa1, 0x24, 0x0e, 0xdd, 0xfb, 0xe6, 0x43,
+	0xe7, 0x4a, 0x52, 0x26, 0x09, 0xfb, 0x9a, 0x33, 0xa5, 0xd1, 0x09, 0x34, 0xcc, 0xf6, 0xb8, 0xf6,
+	0x2c, 0x8d, 0xf9, 0x74, 0x62, 0xc1, 0xe8, 0x5f, 0x68, 0x18, 0xa5, 0x71, 0x3d, 0xf4, 0xfa, 0xad,
+	0xe1, 0x66, 0x59, 0x60, 0x9b, 0x13, 0xfb, 0x44, 0x37, 0xe0, 0xa7, 0xf1, 0x94, 0xa5, 0x0a, 0x7b,
+	0xa1, 0xd7, 0x6f, 0x1f, 0x9f, 0x3c, 0x41, 0xfa, 0x78, 0x97, 0xe8, 0x83, 0x9d, 0xba, 0x58, 0x68,
+	0xb9, 0x1a, 0x42, 0x59, 0xe0, 0x8a, 0x86, 0x54, 0x27, 0x3a, 0x84, 0x60, 0x16, 0x4b, 0x3a, 0xe1,
+	0x14, 0x37, 0x7e, 0x8a, 0x5f, 0x95, 0x2e, 0x37, 0x88, 0x6f, 0xc2, 0x11, 0x45, 0xa7, 0xd0, 0x5c,
+	0xc6, 0x2b, 0x26, 0x71, 0xf3, 0x45, 0x1f, 0x75, 0xb9, 0x41, 0x1c, 0x1e, 0x8d, 0x00, 0x28, 0x4b,
+	0xf9, 0x3d, 0x93, 0x93, 0x58, 0x63, 0xdf, 0x4e, 0x77, 0x23, 0x67, 0x8b, 0x68, 0x6d, 0x8b, 0xe8,
+	0xf3, 0xda, 0x16, 0xc3, 0xed, 0xb2, 0xc0, 0x8f, 0x26, 0x48, 0xab, 0x8a, 0xcf, 0x34, 0xfa, 0x1f,
+	0x3c, 0xad, 0x53, 0x1c, 0x58, 0x8e, 0xdd, 0xdf, 0x38, 0xce, 0x2b, 0xeb, 0x0d, 0x83, 0xb2, 0xc0,
+	0x06, 0x49, 0xcc, 0x03, 0x5d, 0x80, 0xaf, 0x74, 0xac, 0x73, 0x85, 0x37, 0xc3, 0x5a, 0x7f, 0xfb,
+	0xf8, 0xe0, 0x89, 0xd5, 0x3f, 0x59, 0xa0, 0x13, 0xca, 0x0d, 0x91, 0xea, 0x44, 0x3d, 0xf0, 0xe3,
+	0x4c, 0xe4, 0x0b, 0x8d, 0x5b, 0x61, 0xad, 0xef, 0x39, 0x8c, 0xab, 0x90, 0xea, 0x34, 0x06, 0x32,
+	0xe6, 0x53, 0x18, 0x42, 0xef, 0x19, 0x91, 0x8c, 0x77, 0x89, 0x43, 0xa3, 0xbf, 0xc1, 0x57, 0x6c,
+	0x26, 0x99, 0xc6, 0x6d, 0x6b, 0xf0, 0x2a, 0x33, 0x3e, 0x32, 0x16, 0xc5, 0x9d, 0x97, 0xd9, 0xd1,
+	0x82, 0xd1, 0x19, 0x04, 0x77, 0x5c, 0x69, 0x21, 0x57, 0x78, 0x2b, 0xf4, 0x9e, 0x11, 0xdb, 0xfe,
+	0xec, 0x0a, 0x4e, 0xd6, 0x41, 0xf7, 0x0d, 0xb4, 0x1f, 0xd9, 0x06, 0xed, 0x80, 0x37, 0x67, 0x2b,
+	0x77, 0x71, 0x88, 0x09, 0xd1, 0x5f, 0xd0, 0xbc, 0x8f, 0xd3, 0x7c, 0x7d, 0x21, 0x5d, 0xf2, 0xb6,
+	0xfe, 0xba, 0x36, 0x6c, 0x41, 0xb0, 0x8c, 0x57, 0x19, 0x5b, 0xe8, 0xff, 0x7a, 0xe0, 0x3b, 0x39,
+	0x51, 0x1b, 0x82, 0xeb, 0xf1, 0xfb, 0xf1, 0xd5, 0xcd, 0x78, 0x67, 0xc3, 0x24, 0x1f, 0x2f, 0xc6,
+	0xe7, 0xa3, 0xf1, 0xbb, 0x9d, 0xda, 0xd4, 0xb7, 0x3b, 0x9d, 0x7c, 0x1f, 0x00, 0xfe, 0xab, 0x13,
+	0xe1, 0xbb, 0x04, 0x00, 0x00,
+}
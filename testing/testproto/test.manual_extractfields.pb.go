@@ -6,3 +6,8 @@ package mwitkow_testproto
 func (m *PingRequest) ExtractRequestFields(appendToMap map[string]interface{}) {
 	appendToMap["value"] = m.Value
 }
+
+// This is implementing grpc_ctxtags.responseFieldsExtractor
+func (m *PingResponse) ExtractResponseFields(appendToMap map[string]interface{}) {
+	appendToMap["counter"] = m.Counter
+}
@@ -0,0 +1,210 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: validator.proto
+
+// This file is used for testing the field violations reported for the errors of protoc-gen-validate.
+
+package mwitkow_validatortestproto
+
+import (
+	fmt "fmt"
+	_ "github.com/envoyproxy/protoc-gen-validate/validate"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type InnerMessage struct {
+	SomeInteger          int32    `protobuf:"varint,1,opt,name=some_integer,json=someInteger,proto3" json:"some_integer,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InnerMessage) Reset()         { *m = InnerMessage{} }
+func (m *InnerMessage) String() string { return proto.CompactTextString(m) }
+func (*InnerMessage) ProtoMessage()    {}
+func (*InnerMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf1c6ec7c0d80dd5, []int{0}
+}
+
+func (m *InnerMessage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InnerMessage.Unmarshal(m, b)
+}
+func (m *InnerMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InnerMessage.Marshal(b, m, deterministic)
+}
+func (m *InnerMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InnerMessage.Merge(m, src)
+}
+func (m *InnerMessage) XXX_Size() int {
+	return xxx_messageInfo_InnerMessage.Size(m)
+}
+func (m *InnerMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_InnerMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InnerMessage proto.InternalMessageInfo
+
+func (m *InnerMessage) GetSomeInteger() int32 {
+	if m != nil {
+		return m.SomeInteger
+	}
+	return 0
+}
+
+type OuterMessage struct {
+	ImportantString string                   `protobuf:"bytes,1,opt,name=important_string,json=importantString,proto3" json:"important_string,omitempty"`
+	Inner           *InnerMessage            `protobuf:"bytes,2,opt,name=inner,proto3" json:"inner,omitempty"`
+	Inners          []*InnerMessage          `protobuf:"bytes,3,rep,name=inners,proto3" json:"inners,omitempty"`
+	NamedInners     map[string]*InnerMessage `protobuf:"bytes,4,rep,name=named_inners,json=namedInners,proto3" json:"named_inners,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Types that are valid to be assigned to Contact:
+	//	*OuterMessage_Email
+	//	*OuterMessage_Fallback
+	Contact              isOuterMessage_Contact `protobuf_oneof:"contact"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *OuterMessage) Reset()         { *m = OuterMessage{} }
+func (m *OuterMessage) String() string { return proto.CompactTextString(m) }
+func (*OuterMessage) ProtoMessage()    {}
+func (*OuterMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf1c6ec7c0d80dd5, []int{1}
+}
+
+func (m *OuterMessage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OuterMessage.Unmarshal(m, b)
+}
+func (m *OuterMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OuterMessage.Marshal(b, m, deterministic)
+}
+func (m *OuterMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OuterMessage.Merge(m, src)
+}
+func (m *OuterMessage) XXX_Size() int {
+	return xxx_messageInfo_OuterMessage.Size(m)
+}
+func (m *OuterMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_OuterMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OuterMessage proto.InternalMessageInfo
+
+func (m *OuterMessage) GetImportantString() string {
+	if m != nil {
+		return m.ImportantString
+	}
+	return ""
+}
+
+func (m *OuterMessage) GetInner() *InnerMessage {
+	if m != nil {
+		return m.Inner
+	}
+	return nil
+}
+
+func (m *OuterMessage) GetInners() []*InnerMessage {
+	if m != nil {
+		return m.Inners
+	}
+	return nil
+}
+
+func (m *OuterMessage) GetNamedInners() map[string]*InnerMessage {
+	if m != nil {
+		return m.NamedInners
+	}
+	return nil
+}
+
+type isOuterMessage_Contact interface {
+	isOuterMessage_Contact()
+}
+
+type OuterMessage_Email struct {
+	Email string `protobuf:"bytes,5,opt,name=email,proto3,oneof"`
+}
+
+type OuterMessage_Fallback struct {
+	Fallback *InnerMessage `protobuf:"bytes,6,opt,name=fallback,proto3,oneof"`
+}
+
+func (*OuterMessage_Email) isOuterMessage_Contact() {}
+
+func (*OuterMessage_Fallback) isOuterMessage_Contact() {}
+
+func (m *OuterMessage) GetContact() isOuterMessage_Contact {
+	if m != nil {
+		return m.Contact
+	}
+	return nil
+}
+
+func (m *OuterMessage) GetEmail() string {
+	if x, ok := m.GetContact().(*OuterMessage_Email); ok {
+		return x.Email
+	}
+	return ""
+}
+
+func (m *OuterMessage) GetFallback() *InnerMessage {
+	if x, ok := m.GetContact().(*OuterMessage_Fallback); ok {
+		return x.Fallback
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*OuterMessage) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*OuterMessage_Email)(nil),
+		(*OuterMessage_Fallback)(nil),
+	}
+}
+
+func init() {
+	proto.RegisterType((*InnerMessage)(nil), "mwitkow.validatortestproto.InnerMessage")
+	proto.RegisterType((*OuterMessage)(nil), "mwitkow.validatortestproto.OuterMessage")
+	proto.RegisterMapType((map[string]*InnerMessage)(nil), "mwitkow.validatortestproto.OuterMessage.NamedInnersEntry")
+}
+
+func init() { proto.RegisterFile("validator.proto", fileDescriptor_bf1c6ec7c0d80dd5) }
+
+var fileDescriptor_bf1c6ec7c0d80dd5 = []byte{
+	// 363 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0xcf, 0x4a, 0xf3, 0x40,
+	0x14, 0xc5, 0x9b, 0x34, 0x49, 0xdb, 0x69, 0xf8, 0x1a, 0xe6, 0x5b, 0x18, 0xb2, 0x31, 0x14, 0x17,
+	0x5d, 0xd4, 0x08, 0x15, 0x41, 0x45, 0x8a, 0x0c, 0x28, 0xed, 0x42, 0x85, 0x71, 0xe9, 0x9f, 0x3a,
+	0x6d, 0xc7, 0x1a, 0x9a, 0x4c, 0xca, 0xcc, 0xb4, 0xa5, 0x8a, 0x2f, 0xe0, 0x03, 0xf8, 0xb0, 0x59,
+	0x49, 0x26, 0xb5, 0x2d, 0x82, 0x42, 0x77, 0x97, 0x7b, 0xee, 0xf9, 0x71, 0xe6, 0x0c, 0xa8, 0xcd,
+	0x48, 0x14, 0x0e, 0x89, 0x4c, 0x78, 0x30, 0xe1, 0x89, 0x4c, 0xa0, 0x17, 0xcf, 0x43, 0x39, 0x4e,
+	0xe6, 0xc1, 0x4a, 0x90, 0x54, 0x48, 0xa5, 0x79, 0x3b, 0xcb, 0x1d, 0x3d, 0xf8, 0x1e, 0x72, 0x53,
+	0xfd, 0x0c, 0xd8, 0x5d, 0xc6, 0x28, 0xbf, 0xa2, 0x42, 0x90, 0x11, 0x85, 0x4d, 0x60, 0x8b, 0x24,
+	0xa6, 0xbd, 0x90, 0x49, 0x3a, 0xa2, 0xdc, 0xd5, 0x7c, 0xad, 0x61, 0xa2, 0x4a, 0x8a, 0x2c, 0xcf,
+	0x70, 0x86, 0x7e, 0x01, 0x57, 0x33, 0xb9, 0x9b, 0xab, 0xf5, 0x4f, 0x03, 0xd8, 0x37, 0x53, 0xb9,
+	0xb6, 0xb7, 0x81, 0x13, 0xc6, 0x93, 0x84, 0x4b, 0xc2, 0x64, 0x4f, 0x48, 0x1e, 0xb2, 0x91, 0x42,
+	0x54, 0xd0, 0xff, 0x14, 0x39, 0xfc, 0x5f, 0xcb, 0x7e, 0xbc, 0x23, 0xfb, 0xaf, 0x0f, 0x6f, 0xad,
+	0xe6, 0xd1, 0xfb, 0x1e, 0xae, 0xad, 0x8e, 0x6f, 0xd5, 0x2d, 0xec, 0x00, 0x33, 0xcc, 0xe2, 0xb8,
+	0xba, 0xaf, 0x35, 0xaa, 0xad, 0x46, 0xf0, 0xfb, 0x9b, 0x82, 0xcd, 0xdc, 0xa8, 0x9c, 0x22, 0xf3,
+	0x43, 0xd3, 0x1d, 0x0d, 0xe7, 0x00, 0x78, 0x0e, 0x2c, 0x35, 0x08, 0xb7, 0xe8, 0x17, 0xb7, 0x41,
+	0xe1, 0xa5, 0x0f, 0xde, 0x03, 0x9b, 0x91, 0x98, 0x0e, 0x7b, 0x4b, 0x8e, 0xa1, 0x38, 0x27, 0x7f,
+	0x71, 0x36, 0xbb, 0x08, 0xae, 0x33, 0xb3, 0x22, 0x8b, 0x0b, 0x26, 0xf9, 0x02, 0x57, 0xd9, 0x7a,
+	0x03, 0x77, 0x81, 0x49, 0x63, 0x12, 0x46, 0xae, 0xa9, 0xea, 0x29, 0xa5, 0xc8, 0xe0, 0xfa, 0x93,
+	0xd6, 0x29, 0xe0, 0x7c, 0x0f, 0x2f, 0x41, 0xf9, 0x99, 0x44, 0x51, 0x9f, 0x0c, 0xc6, 0xae, 0xb5,
+	0x5d, 0x1b, 0x9d, 0x02, 0x5e, 0x79, 0xbd, 0x17, 0xe0, 0xfc, 0x4c, 0x02, 0x1d, 0x50, 0x1c, 0xd3,
+	0x45, 0xfe, 0x33, 0x38, 0x1b, 0x61, 0x1b, 0x98, 0x33, 0x12, 0x4d, 0xe9, 0xb6, 0xc5, 0xe3, 0xdc,
+	0x76, 0xaa, 0x1f, 0x6b, 0xa8, 0x02, 0x4a, 0x83, 0x84, 0x49, 0x32, 0x90, 0x7d, 0x4b, 0x5d, 0x1e,
+	0x7e, 0x0d, 0x00, 0x14, 0xec, 0x49, 0x72, 0xa5, 0x02, 0x00, 0x00,
+}
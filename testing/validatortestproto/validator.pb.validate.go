@@ -0,0 +1,308 @@
+// Code generated by protoc-gen-validate. DO NOT EDIT.
+// source: validator.proto
+
+package mwitkow_validatortestproto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes"
+)
+
+// ensure the imports are used
+var (
+	_ = bytes.MinRead
+	_ = errors.New("")
+	_ = fmt.Print
+	_ = utf8.UTFMax
+	_ = (*regexp.Regexp)(nil)
+	_ = (*strings.Reader)(nil)
+	_ = net.IPv4len
+	_ = time.Duration(0)
+	_ = (*url.URL)(nil)
+	_ = (*mail.Address)(nil)
+	_ = ptypes.DynamicAny{}
+)
+
+// define the regex for a UUID once up-front
+var _validator_uuidPattern = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+// Validate checks the field values on InnerMessage with the rules defined in
+// the proto definition for this message. If any rules are violated, an error
+// is returned.
+func (m *InnerMessage) Validate() error {
+	if m == nil {
+		return nil
+	}
+
+	if val := m.GetSomeInteger(); val <= 0 || val >= 100 {
+		return InnerMessageValidationError{
+			field:  "SomeInteger",
+			reason: "value must be inside range (0, 100)",
+		}
+	}
+
+	return nil
+}
+
+// InnerMessageValidationError is the validation error returned by
+// InnerMessage.Validate if the designated constraints aren't met.
+type InnerMessageValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e InnerMessageValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e InnerMessageValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e InnerMessageValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e InnerMessageValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e InnerMessageValidationError) ErrorName() string { return "InnerMessageValidationError" }
+
+// Error satisfies the builtin error interface
+func (e InnerMessageValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sInnerMessage.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = InnerMessageValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = InnerMessageValidationError{}
+
+// Validate checks the field values on OuterMessage with the rules defined in
+// the proto definition for this message. If any rules are violated, an error
+// is returned.
+func (m *OuterMessage) Validate() error {
+	if m == nil {
+		return nil
+	}
+
+	if !_OuterMessage_ImportantString_Pattern.MatchString(m.GetImportantString()) {
+		return OuterMessageValidationError{
+			field:  "ImportantString",
+			reason: "value does not match regex pattern \"^[a-z]{2,5}$\"",
+		}
+	}
+
+	if m.GetInner() == nil {
+		return OuterMessageValidationError{
+			field:  "Inner",
+			reason: "value is required",
+		}
+	}
+
+	if v, ok := interface{}(m.GetInner()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return OuterMessageValidationError{
+				field:  "Inner",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
+	for idx, item := range m.GetInners() {
+		_, _ = idx, item
+
+		if v, ok := interface{}(item).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return OuterMessageValidationError{
+					field:  fmt.Sprintf("Inners[%v]", idx),
+					reason: "embedded message failed validation",
+					cause:  err,
+				}
+			}
+		}
+
+	}
+
+	for key, val := range m.GetNamedInners() {
+		_ = val
+
+		// no validation rules for NamedInners[key]
+
+		if v, ok := interface{}(val).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return OuterMessageValidationError{
+					field:  fmt.Sprintf("NamedInners[%v]", key),
+					reason: "embedded message failed validation",
+					cause:  err,
+				}
+			}
+		}
+
+	}
+
+	switch m.Contact.(type) {
+
+	case *OuterMessage_Email:
+
+		if err := m._validateEmail(m.GetEmail()); err != nil {
+			return OuterMessageValidationError{
+				field:  "Email",
+				reason: "value must be a valid email address",
+				cause:  err,
+			}
+		}
+
+	case *OuterMessage_Fallback:
+
+		if v, ok := interface{}(m.GetFallback()).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return OuterMessageValidationError{
+					field:  "Fallback",
+					reason: "embedded message failed validation",
+					cause:  err,
+				}
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *OuterMessage) _validateHostname(host string) error {
+	s := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if len(host) > 253 {
+		return errors.New("hostname cannot exceed 253 characters")
+	}
+
+	for _, part := range strings.Split(s, ".") {
+		if l := len(part); l == 0 || l > 63 {
+			return errors.New("hostname part must be non-empty and cannot exceed 63 characters")
+		}
+
+		if part[0] == '-' {
+			return errors.New("hostname parts cannot begin with hyphens")
+		}
+
+		if part[len(part)-1] == '-' {
+			return errors.New("hostname parts cannot end with hyphens")
+		}
+
+		for _, r := range part {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return fmt.Errorf("hostname parts can only contain alphanumeric characters or hyphens, got %q", string(r))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *OuterMessage) _validateEmail(addr string) error {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return err
+	}
+	addr = a.Address
+
+	if len(addr) > 254 {
+		return errors.New("email addresses cannot exceed 254 characters")
+	}
+
+	parts := strings.SplitN(addr, "@", 2)
+
+	if len(parts[0]) > 64 {
+		return errors.New("email address local phrase cannot exceed 64 characters")
+	}
+
+	return m._validateHostname(parts[1])
+}
+
+// OuterMessageValidationError is the validation error returned by
+// OuterMessage.Validate if the designated constraints aren't met.
+type OuterMessageValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e OuterMessageValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e OuterMessageValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e OuterMessageValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e OuterMessageValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e OuterMessageValidationError) ErrorName() string { return "OuterMessageValidationError" }
+
+// Error satisfies the builtin error interface
+func (e OuterMessageValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sOuterMessage.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = OuterMessageValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = OuterMessageValidationError{}
+
+var _OuterMessage_ImportantString_Pattern = regexp.MustCompile("^[a-z]{2,5}$")
@@ -28,7 +28,15 @@ func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
 		if o.unaryRequestHandlerFunc != nil {
 			o.unaryRequestHandlerFunc(clientSpan, req)
 		}
+		opts, traces := filterCallOptions(opts)
+		var header, trailer metadata.MD
+		if len(traces) > 0 {
+			opts = append(opts, grpc.Header(&header), grpc.Trailer(&trailer))
+		}
 		err := invoker(newCtx, method, req, reply, cc, opts...)
+		if len(traces) > 0 {
+			fillResponseTraces(traces, o.traceIdHeaderName, header, trailer)
+		}
 		finishClientSpan(clientSpan, err)
 		return err
 	}
@@ -42,12 +50,20 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 			return streamer(parentCtx, desc, cc, method, opts...)
 		}
 		newCtx, clientSpan := newClientSpanFromContext(parentCtx, o.tracer, method)
+		opts, traces := filterCallOptions(opts)
 		clientStream, err := streamer(newCtx, desc, cc, method, opts...)
 		if err != nil {
 			finishClientSpan(clientSpan, err)
 			return nil, err
 		}
-		return &tracedClientStream{ClientStream: clientStream, clientSpan: clientSpan}, nil
+		return &tracedClientStream{
+			ClientStream:  clientStream,
+			clientSpan:    clientSpan,
+			events:        newMessageEvents(clientSpan, o),
+			serverStreams: desc.ServerStreams,
+			traces:        traces,
+			traceIdHeader: o.traceIdHeaderName,
+		}, nil
 	}
 }
 
@@ -59,6 +75,13 @@ type tracedClientStream struct {
 	mu              sync.Mutex
 	alreadyFinished bool
 	clientSpan      opentracing.Span
+	// events are the message events of WithMessageEvents, nil when they are disabled. With events, the span is
+	// finished at the end of the stream rather than on CloseSend, so that the received messages are logged.
+	events        *messageEvents
+	serverStreams bool
+	// traces are filled by the first message received, or the end of the stream, for WithResponseTrace.
+	traces        []*ResponseTrace
+	traceIdHeader string
 }
 
 func (s *tracedClientStream) Header() (metadata.MD, error) {
@@ -73,24 +96,60 @@ func (s *tracedClientStream) SendMsg(m interface{}) error {
 	err := s.ClientStream.SendMsg(m)
 	if err != nil {
 		s.finishClientSpan(err)
+	} else {
+		s.logMessage(messageSent, m)
 	}
 	return err
 }
 
 func (s *tracedClientStream) CloseSend() error {
 	err := s.ClientStream.CloseSend()
-	s.finishClientSpan(err)
+	if err != nil || s.events == nil {
+		s.finishClientSpan(err)
+	}
 	return err
 }
 
 func (s *tracedClientStream) RecvMsg(m interface{}) error {
 	err := s.ClientStream.RecvMsg(m)
+	s.fillResponseTraces(err)
 	if err != nil {
 		s.finishClientSpan(err)
+	} else {
+		s.logMessage(messageReceived, m)
+		if s.events != nil && !s.serverStreams {
+			// The single response of a client-streaming call ends the stream.
+			s.finishClientSpan(nil)
+		}
 	}
 	return err
 }
 
+func (s *tracedClientStream) fillResponseTraces(recvErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.traces) == 0 {
+		return
+	}
+	// The header was received with the message, and the trailer only comes at the end of the stream.
+	header, _ := s.ClientStream.Header()
+	filled := fillResponseTraces(s.traces, s.traceIdHeader, header)
+	if !filled && recvErr != nil {
+		filled = fillResponseTraces(s.traces, s.traceIdHeader, s.ClientStream.Trailer())
+	}
+	if filled || recvErr != nil {
+		s.traces = nil
+	}
+}
+
+func (s *tracedClientStream) logMessage(direction string, m interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.alreadyFinished {
+		s.events.log(direction, m)
+	}
+}
+
 func (s *tracedClientStream) finishClientSpan(err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -13,6 +13,15 @@ For a service that sends out requests and receives requests, you *need* to use b
 not have the appropriate requests propagated.
 
 All server-side spans are tagged with grpc_ctxtags information.
+The trace and span IDs, and whether the trace is sampled, are set in the tags from the headers of the tracer in the
+Jaeger, W3C Trace Context and Zipkin B3 formats; `WithTraceFormats` restricts the formats parsed.
+
+With `WithTraceIdInResponse`, the server interceptors send the trace ID back to callers in the response header or
+trailer (`x-trace-id` by default), so that failures reported by customers can be correlated with the traces. Clients
+get it with the `WithResponseTrace` call option.
+
+With `WithMessageEvents`, the spans of streaming RPCs also get an event for the messages sent and received, sampled
+and capped for very long streams.
 
 For more information see:
 http://opentracing.io/documentation/
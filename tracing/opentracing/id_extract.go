@@ -12,71 +12,302 @@ const (
 	TagTraceId           = "trace.traceid"
 	TagSpanId            = "trace.spanid"
 	TagSampled           = "trace.sampled"
+	TagTraceState        = "trace.tracestate"
 	jaegerNotSampledFlag = "0"
+
+	w3cTraceParentHeader = "traceparent"
+	w3cTraceStateHeader  = "tracestate"
+	b3SingleHeader       = "b3"
+	b3TraceIdHeader      = "x-b3-traceid"
+	b3SpanIdHeader       = "x-b3-spanid"
+	b3ParentSpanIdHeader = "x-b3-parentspanid"
+	b3SampledHeader      = "x-b3-sampled"
+	b3FlagsHeader        = "x-b3-flags"
+
+	// w3cMaxTraceStateMembers is the maximum number of list-members of a tracestate.
+	w3cMaxTraceStateMembers = 32
+)
+
+// TraceFormat is a set of formats of the trace headers that the interceptors recognize when setting the trace and
+// span IDs in the tags, combined with `|`.
+type TraceFormat uint
+
+const (
+	// TraceFormatJaeger is the `{trace-id}:{span-id}:{parent-span-id}:{flags}` format of Jaeger, in the header set by
+	// WithTraceHeaderName (`uber-trace-id` by default).
+	TraceFormatJaeger TraceFormat = 1 << iota
+	// TraceFormatW3C is the W3C Trace Context format, in the `traceparent` and `tracestate` headers.
+	TraceFormatW3C
+	// TraceFormatB3Single is the single header format of Zipkin B3, in the `b3` header.
+	TraceFormatB3Single
+	// TraceFormatB3Multi is the multiple headers format of Zipkin B3, in the `x-b3-*` headers.
+	TraceFormatB3Multi
+	// TraceFormatGuess guesses the IDs from the headers of other formats, by the names of their keys: keys containing
+	// `traceid`, `spanid` or `sampled`, or ending with `trace-id` or `parent-id` (e.g. Datadog). The guesses never
+	// override the IDs of the other formats.
+	TraceFormatGuess
+
+	// AllTraceFormats are all the formats above, the default.
+	AllTraceFormats = TraceFormatJaeger | TraceFormatW3C | TraceFormatB3Single | TraceFormatB3Multi | TraceFormatGuess
 )
 
 // injectOpentracingIdsToTags writes trace data to ctxtags.
 // This is done in an incredibly hacky way, because the public-facing interface of opentracing doesn't give access to
 // the TraceId and SpanId of the SpanContext. Only the Tracer's Inject/Extract methods know what these are.
-// Most tracers have them encoded as keys with 'traceid' and 'spanid':
-// https://github.com/openzipkin/zipkin-go-opentracing/blob/594640b9ef7e5c994e8d9499359d693c032d738c/propagation_ot.go#L29
-// https://github.com/opentracing/basictracer-go/blob/1b32af207119a14b1b231d451df3ed04a72efebf/propagation_ot.go#L26
+// The headers of well-known formats are parsed according to their specifications:
 // Jaeger from Uber use one-key schema with next format '{trace-id}:{span-id}:{parent-span-id}:{flags}'
 // https://www.jaegertracing.io/docs/client-libraries/#trace-span-identity
+// W3C Trace Context uses 'traceparent' with the format '{version}-{trace-id}-{parent-id}-{trace-flags}'
+// https://www.w3.org/TR/trace-context/
+// Zipkin B3 uses either 'b3' with the format '{trace-id}-{span-id}-{sampling-state}-{parent-span-id}', or 'x-b3-*' keys
+// https://github.com/openzipkin/b3-propagation
+// Other tracers have them encoded as keys with 'traceid' and 'spanid':
+// https://github.com/openzipkin/zipkin-go-opentracing/blob/594640b9ef7e5c994e8d9499359d693c032d738c/propagation_ot.go#L29
+// https://github.com/opentracing/basictracer-go/blob/1b32af207119a14b1b231d451df3ed04a72efebf/propagation_ot.go#L26
 // Datadog uses keys ending with 'trace-id' and 'parent-id' (for span) by default:
 // https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/textmap.go#L77
-func injectOpentracingIdsToTags(traceHeaderName string, span opentracing.Span, tags grpc_ctxtags.Tags) {
+func injectOpentracingIdsToTags(traceHeaderName string, formats TraceFormat, span opentracing.Span, tags grpc_ctxtags.Tags) {
 	if err := span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders,
-		&tagsCarrier{Tags: tags, traceHeaderName: traceHeaderName}); err != nil {
+		&tagsCarrier{Tags: tags, traceHeaderName: traceHeaderName, formats: formats}); err != nil {
 		grpclog.Infof("grpc_opentracing: failed extracting trace info into ctx %v", err)
 	}
 }
 
-// tagsCarrier is a really hacky way of
+// tagsCarrier is a really hacky way of getting the IDs of a span: it is an opentracing.TextMapWriter that parses the
+// headers written by the tracer into tags.
 type tagsCarrier struct {
 	grpc_ctxtags.Tags
 	traceHeaderName string
+	// formats are the trace formats to parse, all of them if 0.
+	formats TraceFormat
+	// parsed are the tags set from a well-known format, that guesses must not override.
+	parsed map[string]bool
+	// b3Debug is set by the B3 debug flag, which implies that the trace is sampled.
+	b3Debug bool
 }
 
 func (t *tagsCarrier) Set(key, val string) {
 	key = strings.ToLower(key)
 
-	if key == t.traceHeaderName {
-		parts := strings.Split(val, ":")
-		if len(parts) == 4 {
-			t.Tags.Set(TagTraceId, parts[0])
-			t.Tags.Set(TagSpanId, parts[1])
+	switch {
+	case key == t.traceHeaderName && t.enabled(TraceFormatJaeger):
+		if t.setJaeger(val) {
+			return
+		}
+	case key == w3cTraceParentHeader && t.enabled(TraceFormatW3C):
+		t.setW3CTraceParent(val)
+		return
+	case key == w3cTraceStateHeader && t.enabled(TraceFormatW3C):
+		t.setW3CTraceState(val)
+		return
+	case key == b3SingleHeader && t.enabled(TraceFormatB3Single):
+		t.setB3Single(val)
+		return
+	case strings.HasPrefix(key, "x-b3-") && t.enabled(TraceFormatB3Multi):
+		t.setB3Multi(key, val)
+		return
+	}
 
-			if parts[3] != jaegerNotSampledFlag {
-				t.Tags.Set(TagSampled, "true")
-			} else {
-				t.Tags.Set(TagSampled, "false")
-			}
+	if t.enabled(TraceFormatGuess) {
+		t.guess(key, val)
+	}
+}
+
+func (t *tagsCarrier) enabled(format TraceFormat) bool {
+	return t.formats == 0 || t.formats&format != 0
+}
+
+// setParsed sets a tag parsed from a well-known format.
+func (t *tagsCarrier) setParsed(key string, val string) {
+	if t.parsed == nil {
+		t.parsed = make(map[string]bool)
+	}
+	t.parsed[key] = true
+	t.Tags.Set(key, val)
+}
+
+// setGuessed sets a guessed tag, unless it was parsed from a well-known format.
+func (t *tagsCarrier) setGuessed(key string, val string) {
+	if !t.parsed[key] {
+		t.Tags.Set(key, val)
+	}
+}
+
+func (t *tagsCarrier) setJaeger(val string) bool {
+	parts := strings.Split(val, ":")
+	if len(parts) != 4 {
+		return false
+	}
+	t.setParsed(TagTraceId, parts[0])
+	t.setParsed(TagSpanId, parts[1])
+	if parts[3] != jaegerNotSampledFlag {
+		t.setParsed(TagSampled, "true")
+	} else {
+		t.setParsed(TagSampled, "false")
+	}
+	return true
+}
+
+// setW3CTraceParent parses a `traceparent` header, ignoring malformed ones.
+func (t *tagsCarrier) setW3CTraceParent(val string) {
+	parts := strings.Split(strings.TrimSpace(val), "-")
+	if len(parts) < 4 {
+		return
+	}
+	version, traceId, spanId, flags := parts[0], parts[1], parts[2], parts[3]
+	// Version 00 has exactly 4 fields, later versions may add some.
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return
+	}
+	if !isLowerHex(traceId, 32) || isZeroId(traceId) || !isLowerHex(spanId, 16) || isZeroId(spanId) || !isLowerHex(flags, 2) {
+		return
+	}
+	t.setParsed(TagTraceId, traceId)
+	t.setParsed(TagSpanId, spanId)
+	// The sampled flag is the least significant bit of the trace flags.
+	if strings.IndexByte("13579bdf", flags[1]) >= 0 {
+		t.setParsed(TagSampled, "true")
+	} else {
+		t.setParsed(TagSampled, "false")
+	}
+}
 
+// setW3CTraceState parses a `tracestate` header, ignoring malformed ones. Its list-members are kept as they are, only
+// stripped of optional white space and empty members.
+func (t *tagsCarrier) setW3CTraceState(val string) {
+	var members []string
+	for _, member := range strings.Split(val, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		eq := strings.IndexByte(member, '=')
+		if eq <= 0 || eq == len(member)-1 || strings.ContainsAny(member, " \t") {
 			return
 		}
+		members = append(members, member)
+	}
+	if len(members) == 0 || len(members) > w3cMaxTraceStateMembers {
+		return
+	}
+	t.setParsed(TagTraceState, strings.Join(members, ","))
+}
+
+// setB3Single parses a `b3` header, ignoring malformed ones. It is either `{sampling-state}` alone, or
+// `{trace-id}-{span-id}`, optionally followed by `-{sampling-state}` and `-{parent-span-id}`.
+func (t *tagsCarrier) setB3Single(val string) {
+	parts := strings.Split(strings.TrimSpace(val), "-")
+	if len(parts) == 1 {
+		if sampled, ok := b3Sampled(parts[0]); ok {
+			t.setParsed(TagSampled, sampled)
+		}
+		return
+	}
+	if len(parts) > 4 || !isB3TraceId(parts[0]) || !isLowerHex(parts[1], 16) || isZeroId(parts[1]) {
+		return
+	}
+	sampled := ""
+	if len(parts) >= 3 {
+		var ok bool
+		if sampled, ok = b3Sampled(parts[2]); !ok {
+			return
+		}
+	}
+	if len(parts) == 4 && (!isLowerHex(parts[3], 16) || isZeroId(parts[3])) {
+		return
+	}
+	t.setParsed(TagTraceId, parts[0])
+	t.setParsed(TagSpanId, parts[1])
+	if sampled != "" {
+		t.setParsed(TagSampled, sampled)
+	}
+}
+
+// setB3Multi parses one of the `x-b3-*` headers, ignoring malformed ones.
+func (t *tagsCarrier) setB3Multi(key, val string) {
+	val = strings.TrimSpace(val)
+	switch key {
+	case b3TraceIdHeader:
+		if isB3TraceId(val) {
+			t.setParsed(TagTraceId, val)
+		}
+	case b3SpanIdHeader:
+		if isLowerHex(val, 16) && !isZeroId(val) {
+			t.setParsed(TagSpanId, val)
+		}
+	case b3SampledHeader:
+		switch val {
+		case "1", "true":
+			t.setParsed(TagSampled, "true")
+		case "0", "false":
+			if !t.b3Debug {
+				t.setParsed(TagSampled, "false")
+			}
+		}
+	case b3FlagsHeader:
+		if val == "1" {
+			t.b3Debug = true
+			t.setParsed(TagSampled, "true")
+		}
 	}
+}
 
+// b3Sampled converts a B3 sampling state: `1` for sampled, `0` for not sampled and `d` for debug, that is sampled.
+func b3Sampled(state string) (string, bool) {
+	switch state {
+	case "1", "d":
+		return "true", true
+	case "0":
+		return "false", true
+	}
+	return "", false
+}
+
+func (t *tagsCarrier) guess(key, val string) {
 	if strings.Contains(key, "traceid") {
-		t.Tags.Set(TagTraceId, val) // this will most likely be base-16 (hex) encoded
+		t.setGuessed(TagTraceId, val) // this will most likely be base-16 (hex) encoded
 	}
 
-	if strings.Contains(key, "spanid") && !strings.Contains(strings.ToLower(key), "parent") {
-		t.Tags.Set(TagSpanId, val) // this will most likely be base-16 (hex) encoded
+	if strings.Contains(key, "spanid") && !strings.Contains(key, "parent") {
+		t.setGuessed(TagSpanId, val) // this will most likely be base-16 (hex) encoded
 	}
 
 	if strings.Contains(key, "sampled") {
 		switch val {
 		case "true", "false":
-			t.Tags.Set(TagSampled, val)
+			t.setGuessed(TagSampled, val)
 		}
 	}
 
 	if strings.HasSuffix(key, "trace-id") {
-		t.Tags.Set(TagTraceId, val)
+		t.setGuessed(TagTraceId, val)
 	}
 
 	if strings.HasSuffix(key, "parent-id") {
-		t.Tags.Set(TagSpanId, val)
+		t.setGuessed(TagSpanId, val)
 	}
 }
+
+// isB3TraceId tells whether s is a B3 trace ID: 64 or 128 bits, hex-encoded, not all zeros.
+func isB3TraceId(s string) bool {
+	return (isLowerHex(s, 16) || isLowerHex(s, 32)) && !isZeroId(s)
+}
+
+// isLowerHex tells whether s is made of n lowercase hexadecimal digits.
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZeroId tells whether an hex-encoded ID is all zeros, which is invalid.
+func isZeroId(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
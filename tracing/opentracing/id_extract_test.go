@@ -4,6 +4,7 @@ import (
 	"fmt"
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -30,3 +31,160 @@ func TestTagsCarrier_Set_JaegerTraceFormat(t *testing.T) {
 		TagSampled: "true",
 	}, c.Tags.Values())
 }
+
+type header struct {
+	key, val string
+}
+
+func TestTagsCarrier_Set_Formats(t *testing.T) {
+	const (
+		traceId128 = "4bf92f3577b34da6a3ce929d0e0e4736"
+		traceId64  = "a3ce929d0e0e4736"
+		spanId     = "00f067aa0ba902b7"
+		parentId   = "05e3ac9a4f6e3b90"
+	)
+	for _, tcase := range []struct {
+		name     string
+		formats  TraceFormat
+		headers  []header
+		expected map[string]interface{}
+	}{
+		{
+			name:     "w3c sampled",
+			headers:  []header{{"traceparent", "00-" + traceId128 + "-" + spanId + "-01"}},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "true"},
+		},
+		{
+			name:     "w3c not sampled",
+			headers:  []header{{"Traceparent", "00-" + traceId128 + "-" + spanId + "-00"}},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "false"},
+		},
+		{
+			name:     "w3c other flags",
+			headers:  []header{{"traceparent", "00-" + traceId128 + "-" + spanId + "-03"}},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "true"},
+		},
+		{
+			name:     "w3c future version with more fields",
+			headers:  []header{{"traceparent", "cc-" + traceId128 + "-" + spanId + "-01-what-the-future-will-be"}},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "true"},
+		},
+		{
+			name: "w3c with tracestate",
+			headers: []header{
+				{"tracestate", "congo=t61rcWkgMzE, ,rojo=00f067aa0ba902b7"},
+				{"traceparent", "00-" + traceId128 + "-" + spanId + "-01"},
+			},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "true", TagTraceState: "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"},
+		},
+		{name: "w3c version 00 with more fields", headers: []header{{"traceparent", "00-" + traceId128 + "-" + spanId + "-01-extra"}}, expected: map[string]interface{}{}},
+		{name: "w3c forbidden version", headers: []header{{"traceparent", "ff-" + traceId128 + "-" + spanId + "-01"}}, expected: map[string]interface{}{}},
+		{name: "w3c too few fields", headers: []header{{"traceparent", "00-" + traceId128 + "-" + spanId}}, expected: map[string]interface{}{}},
+		{name: "w3c zero trace id", headers: []header{{"traceparent", "00-00000000000000000000000000000000-" + spanId + "-01"}}, expected: map[string]interface{}{}},
+		{name: "w3c zero span id", headers: []header{{"traceparent", "00-" + traceId128 + "-0000000000000000-01"}}, expected: map[string]interface{}{}},
+		{name: "w3c short trace id", headers: []header{{"traceparent", "00-" + traceId64 + "-" + spanId + "-01"}}, expected: map[string]interface{}{}},
+		{name: "w3c uppercase", headers: []header{{"traceparent", "00-" + strings.ToUpper(traceId128) + "-" + spanId + "-01"}}, expected: map[string]interface{}{}},
+		{name: "w3c bad flags", headers: []header{{"traceparent", "00-" + traceId128 + "-" + spanId + "-1"}}, expected: map[string]interface{}{}},
+		{name: "w3c empty", headers: []header{{"traceparent", ""}}, expected: map[string]interface{}{}},
+		{name: "w3c tracestate without value", headers: []header{{"tracestate", "congo="}}, expected: map[string]interface{}{}},
+		{name: "w3c tracestate without key", headers: []header{{"tracestate", "=t61rcWkgMzE"}}, expected: map[string]interface{}{}},
+		{name: "w3c tracestate with inner spaces", headers: []header{{"tracestate", "congo=t61 rcWkgMzE"}}, expected: map[string]interface{}{}},
+		{name: "w3c tracestate too long", headers: []header{{"tracestate", strings.Repeat("a=b,", 33)}}, expected: map[string]interface{}{}},
+		{
+			name:     "b3 single sampled",
+			headers:  []header{{"b3", traceId128 + "-" + spanId + "-1"}},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "true"},
+		},
+		{
+			name:     "b3 single 64 bits trace id with parent",
+			headers:  []header{{"b3", traceId64 + "-" + spanId + "-0-" + parentId}},
+			expected: map[string]interface{}{TagTraceId: traceId64, TagSpanId: spanId, TagSampled: "false"},
+		},
+		{
+			name:     "b3 single debug",
+			headers:  []header{{"b3", traceId128 + "-" + spanId + "-d"}},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "true"},
+		},
+		{
+			name:     "b3 single deferred sampling",
+			headers:  []header{{"b3", traceId128 + "-" + spanId}},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId},
+		},
+		{name: "b3 single sampling only", headers: []header{{"b3", "0"}}, expected: map[string]interface{}{TagSampled: "false"}},
+		{name: "b3 single bad sampling", headers: []header{{"b3", traceId128 + "-" + spanId + "-true"}}, expected: map[string]interface{}{}},
+		{name: "b3 single bad trace id", headers: []header{{"b3", "abc-" + spanId + "-1"}}, expected: map[string]interface{}{}},
+		{name: "b3 single zero span id", headers: []header{{"b3", traceId128 + "-0000000000000000-1"}}, expected: map[string]interface{}{}},
+		{name: "b3 single bad parent", headers: []header{{"b3", traceId128 + "-" + spanId + "-1-xyz"}}, expected: map[string]interface{}{}},
+		{name: "b3 single too many fields", headers: []header{{"b3", traceId128 + "-" + spanId + "-1-" + parentId + "-1"}}, expected: map[string]interface{}{}},
+		{name: "b3 single bad sampling only", headers: []header{{"b3", "2"}}, expected: map[string]interface{}{}},
+		{
+			name: "b3 multi",
+			headers: []header{
+				{"X-B3-TraceId", traceId128},
+				{"X-B3-SpanId", spanId},
+				{"X-B3-ParentSpanId", parentId},
+				{"X-B3-Sampled", "1"},
+			},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "true"},
+		},
+		{
+			name:     "b3 multi legacy sampled",
+			headers:  []header{{"x-b3-traceid", traceId64}, {"x-b3-spanid", spanId}, {"x-b3-sampled", "false"}},
+			expected: map[string]interface{}{TagTraceId: traceId64, TagSpanId: spanId, TagSampled: "false"},
+		},
+		{
+			name:     "b3 multi debug wins over not sampled",
+			headers:  []header{{"x-b3-flags", "1"}, {"x-b3-sampled", "0"}},
+			expected: map[string]interface{}{TagSampled: "true"},
+		},
+		{
+			name:     "b3 multi malformed",
+			headers:  []header{{"x-b3-traceid", "not-hex"}, {"x-b3-spanid", "0000000000000000"}, {"x-b3-sampled", "yes"}, {"x-b3-flags", "0"}},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "jaeger",
+			headers:  []header{{"uber-trace-id", traceId64 + ":" + spanId + ":" + parentId + ":0"}},
+			expected: map[string]interface{}{TagTraceId: traceId64, TagSpanId: spanId, TagSampled: "false"},
+		},
+		{
+			name:     "guess",
+			headers:  []header{{"ot-tracer-traceid", "1337"}, {"ot-tracer-spanid", "999"}, {"ot-tracer-sampled", "true"}},
+			expected: map[string]interface{}{TagTraceId: "1337", TagSpanId: "999", TagSampled: "true"},
+		},
+		{
+			name:     "guess datadog",
+			headers:  []header{{"x-datadog-trace-id", "1337"}, {"x-datadog-parent-id", "999"}},
+			expected: map[string]interface{}{TagTraceId: "1337", TagSpanId: "999"},
+		},
+		{
+			name:     "guesses do not override parsed formats",
+			headers:  []header{{"traceparent", "00-" + traceId128 + "-" + spanId + "-01"}, {"ot-tracer-traceid", "1337"}},
+			expected: map[string]interface{}{TagTraceId: traceId128, TagSpanId: spanId, TagSampled: "true"},
+		},
+		{
+			name:     "disabled formats are ignored",
+			formats:  TraceFormatB3Multi,
+			headers:  []header{{"traceparent", "00-" + traceId128 + "-" + spanId + "-01"}, {"b3", traceId64 + "-" + spanId + "-1"}, {"ot-tracer-traceid", "1337"}},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "disabled b3 multi falls back to guesses",
+			formats:  TraceFormatW3C | TraceFormatGuess,
+			headers:  []header{{"x-b3-traceid", traceId64}, {"x-b3-sampled", "1"}},
+			expected: map[string]interface{}{TagTraceId: traceId64},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			c := &tagsCarrier{
+				Tags:            grpc_ctxtags.NewTags(),
+				traceHeaderName: "uber-trace-id",
+				formats:         tcase.formats,
+			}
+			for _, h := range tcase.headers {
+				c.Set(h.key, h.val)
+			}
+			assert.Equal(t, tcase.expected, c.Tags.Values())
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_opentracing
+
+import (
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	messageSent     = "SENT"
+	messageReceived = "RECEIVED"
+)
+
+// messageEvents logs the messages of a stream on its span, according to WithMessageEvents.
+type messageEvents struct {
+	span  opentracing.Span
+	every uint
+	limit uint
+
+	mu        sync.Mutex
+	sent      int64
+	received  int64
+	logged    uint
+	truncated bool
+}
+
+func newMessageEvents(span opentracing.Span, o *options) *messageEvents {
+	if o.messageEventsEvery == 0 {
+		return nil
+	}
+	return &messageEvents{span: span, every: o.messageEventsEvery, limit: o.messageEventsLimit}
+}
+
+// log logs a message sent or received, numbered from 1 in each direction. It is a no-op on nil messageEvents, so
+// that streams don't need to check whether the events are enabled.
+func (e *messageEvents) log(direction string, m interface{}) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var id int64
+	if direction == messageSent {
+		e.sent++
+		id = e.sent
+	} else {
+		e.received++
+		id = e.received
+	}
+	if uint64(id-1)%uint64(e.every) != 0 {
+		return
+	}
+	if e.limit > 0 && e.logged >= e.limit {
+		if !e.truncated {
+			e.truncated = true
+			e.span.LogFields(log.String("event", "message events truncated"), log.Uint64("message.events_limit", uint64(e.limit)))
+		}
+		return
+	}
+	e.logged++
+	fields := []log.Field{
+		log.String("event", "message"),
+		log.String("message.type", direction),
+		log.Int64("message.id", id),
+	}
+	if pm, ok := m.(proto.Message); ok {
+		fields = append(fields, log.Int("message.uncompressed_size", proto.Size(pm)))
+	}
+	e.span.LogFields(fields...)
+}
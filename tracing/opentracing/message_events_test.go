@@ -0,0 +1,149 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_opentracing_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
+)
+
+func makeMessageEventsInterceptorTestSuite(mockTracer *mocktracer.MockTracer, every, limit uint) *grpc_testing.InterceptorTestSuite {
+	opts := []grpc_opentracing.Option{
+		grpc_opentracing.WithTracer(mockTracer),
+		grpc_opentracing.WithMessageEvents(every, limit),
+	}
+	return &grpc_testing.InterceptorTestSuite{
+		ClientOpts: []grpc.DialOption{
+			grpc.WithStreamInterceptor(grpc_opentracing.StreamClientInterceptor(opts...)),
+		},
+		ServerOpts: []grpc.ServerOption{
+			grpc.StreamInterceptor(grpc_opentracing.StreamServerInterceptor(opts...)),
+		},
+	}
+}
+
+func TestMessageEventsSuite(t *testing.T) {
+	mockTracer := mocktracer.New()
+	suite.Run(t, &MessageEventsSuite{
+		InterceptorTestSuite: makeMessageEventsInterceptorTestSuite(mockTracer, 1, 0),
+		mockTracer:           mockTracer,
+	})
+}
+
+type MessageEventsSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	mockTracer *mocktracer.MockTracer
+}
+
+func (s *MessageEventsSuite) SetupTest() {
+	s.mockTracer.Reset()
+}
+
+// messageEvents returns the message events of the client and server spans of a method, as `<type> <id>`, and whether
+// the events of a span were truncated.
+func messageEvents(t *testing.T, mockTracer *mocktracer.MockTracer, methodName string) (client, server []string, truncated bool) {
+	for _, span := range mockTracer.FinishedSpans() {
+		if span.OperationName != methodName {
+			continue
+		}
+		var events []string
+		for _, record := range span.Logs() {
+			fields := make(map[string]string)
+			for _, field := range record.Fields {
+				fields[field.Key] = field.ValueString
+			}
+			if fields["event"] == "message events truncated" {
+				truncated = true
+			}
+			if fields["event"] != "message" {
+				continue
+			}
+			assert.False(t, record.Timestamp.IsZero(), "message events must have a timestamp")
+			assert.NotEmpty(t, fields["message.uncompressed_size"], "message events must have the size of the message")
+			events = append(events, fields["message.type"]+" "+fields["message.id"])
+		}
+		if span.Tag("span.kind") == ext.SpanKindRPCClientEnum {
+			client = events
+		} else {
+			server = events
+		}
+	}
+	return client, server, truncated
+}
+
+func (s *MessageEventsSuite) TestPingStream_LogsMessages() {
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for i := 0; i < 3; i++ {
+		require.NoError(s.T(), stream.Send(goodPing), "sending should not fail")
+		_, err := stream.Recv()
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	require.NoError(s.T(), stream.CloseSend(), "closing should not fail")
+	_, err = stream.Recv()
+	require.Equal(s.T(), io.EOF, err, "the stream must end")
+
+	client, server, truncated := messageEvents(s.T(), s.mockTracer, "/mwitkow.testproto.TestService/PingStream")
+	assert.Equal(s.T(), []string{"SENT 1", "RECEIVED 1", "SENT 2", "RECEIVED 2", "SENT 3", "RECEIVED 3"}, client)
+	assert.Equal(s.T(), []string{"RECEIVED 1", "SENT 1", "RECEIVED 2", "SENT 2", "RECEIVED 3", "SENT 3"}, server)
+	assert.False(s.T(), truncated, "events must not be truncated without a limit")
+}
+
+func (s *MessageEventsSuite) TestPingList_LogsMessagesUntilTheEnd() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+
+	client, server, _ := messageEvents(s.T(), s.mockTracer, "/mwitkow.testproto.TestService/PingList")
+	require.Len(s.T(), client, grpc_testing.ListResponseCount+1, "the received messages must be logged after CloseSend")
+	assert.Equal(s.T(), "SENT 1", client[0])
+	assert.Equal(s.T(), fmt.Sprintf("RECEIVED %d", grpc_testing.ListResponseCount), client[grpc_testing.ListResponseCount])
+	assert.Len(s.T(), server, grpc_testing.ListResponseCount+1)
+}
+
+func TestMessageEventsSuite_SamplingAndLimit(t *testing.T) {
+	mockTracer := mocktracer.New()
+	suite.Run(t, &SampledMessageEventsSuite{
+		InterceptorTestSuite: makeMessageEventsInterceptorTestSuite(mockTracer, 2, 3),
+		mockTracer:           mockTracer,
+	})
+}
+
+type SampledMessageEventsSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	mockTracer *mocktracer.MockTracer
+}
+
+func (s *SampledMessageEventsSuite) TestPingList_SamplesAndCapsMessages() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+
+	client, _, truncated := messageEvents(s.T(), s.mockTracer, "/mwitkow.testproto.TestService/PingList")
+	assert.Equal(s.T(), []string{"SENT 1", "RECEIVED 1", "RECEIVED 3"}, client, "one message out of 2 must be logged, up to 3")
+	assert.True(s.T(), truncated, "the span must tell that events were truncated")
+}
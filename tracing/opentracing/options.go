@@ -33,6 +33,11 @@ type options struct {
 	traceHeaderName         string
 	unaryRequestHandlerFunc UnaryRequestHandlerFunc
 	opNameFunc              OpNameFunc
+	messageEventsEvery      uint
+	messageEventsLimit      uint
+	traceFormats            TraceFormat
+	traceIdLocation         TraceIdLocation
+	traceIdHeaderName       string
 }
 
 func evaluateOptions(opts []Option) *options {
@@ -47,6 +52,9 @@ func evaluateOptions(opts []Option) *options {
 	if optCopy.traceHeaderName == "" {
 		optCopy.traceHeaderName = "uber-trace-id"
 	}
+	if optCopy.traceIdHeaderName == "" {
+		optCopy.traceIdHeaderName = DefaultTraceIdHeader
+	}
 	return optCopy
 }
 
@@ -67,6 +75,14 @@ func WithTraceHeaderName(name string) Option {
 	}
 }
 
+// WithTraceFormats restricts the formats of the trace headers parsed to set the trace and span IDs in the tags, e.g.
+// `WithTraceFormats(TraceFormatW3C | TraceFormatB3Multi)`. All the formats are parsed by default.
+func WithTraceFormats(formats TraceFormat) Option {
+	return func(o *options) {
+		o.traceFormats = formats
+	}
+}
+
 // WithTracer sets a custom tracer to be used for this middleware, otherwise the opentracing.GlobalTracer is used.
 func WithTracer(tracer opentracing.Tracer) Option {
 	return func(o *options) {
@@ -87,3 +103,33 @@ func WithOpName(f OpNameFunc) Option {
 		o.opNameFunc = f
 	}
 }
+
+// WithMessageEvents logs an event on the span of streaming RPCs for the messages sent and received, on both client
+// and server sides. Events have the direction (`SENT` or `RECEIVED`), the sequence number in that direction, starting
+// at 1, and the serialized size of the message.
+//
+// Only one message out of every is logged in each direction, e.g. the 1st, 11th, 21st... with 10, and at most limit
+// events are logged per stream (0 meaning no limit), so that very long streams don't flood the spans. An every of 0
+// disables the events.
+//
+// With message events, the spans of client streams are finished at the end of the stream, when `RecvMsg` returns an
+// error (`io.EOF` included) or the response of a client-streaming call, rather than on `CloseSend`.
+func WithMessageEvents(every uint, limit uint) Option {
+	return func(o *options) {
+		o.messageEventsEvery = every
+		o.messageEventsLimit = limit
+	}
+}
+
+// WithTraceIdInResponse makes the server interceptors send the trace ID of calls back to the callers, so that they
+// can report it along with failures. The trace ID is sent in the header or the trailer of the response with the key
+// name (DefaultTraceIdHeader if empty), and whether the trace is sampled (`true` or `false`) with the key
+// `<name>-sampled`.
+//
+// On the client interceptors, it sets the key read by the WithResponseTrace call option.
+func WithTraceIdInResponse(location TraceIdLocation, name string) Option {
+	return func(o *options) {
+		o.traceIdLocation = location
+		o.traceIdHeaderName = name
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_opentracing
+
+import (
+	"context"
+
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	opentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// DefaultTraceIdHeader is the metadata key of the trace ID sent back to callers with WithTraceIdInResponse.
+	DefaultTraceIdHeader = "x-trace-id"
+	// traceSampledSuffix is appended to the key of the trace ID for the key of the sampled flag.
+	traceSampledSuffix = "-sampled"
+)
+
+// TraceIdLocation is where the server interceptors send the trace ID back to callers.
+type TraceIdLocation int
+
+const (
+	// TraceIdInHeader sends the trace ID in the response header, available to callers before the messages.
+	TraceIdInHeader TraceIdLocation = iota + 1
+	// TraceIdInTrailer sends the trace ID in the response trailer, available to callers at the end of the call.
+	TraceIdInTrailer
+)
+
+// ResponseTrace is the trace of a call, as sent back by the server.
+type ResponseTrace struct {
+	// TraceId is empty when the server didn't send the trace ID back.
+	TraceId string
+	Sampled bool
+}
+
+// responseTraceCallOption is a grpc.CallOption that is local to grpc_opentracing.
+type responseTraceCallOption struct {
+	grpc.EmptyCallOption // make sure we implement private after() and before() fields so we don't panic.
+	trace                *ResponseTrace
+}
+
+// WithResponseTrace is a grpc.CallOption that fills trace with the trace ID sent back by a server using
+// WithTraceIdInResponse, for the calls traced by the client interceptors. The trace is filled when the unary call
+// returns, or when the first message of a stream is received, at the latest at the end of the stream.
+//
+// The trace ID is read from the key set by WithTraceIdInResponse on the client interceptors, DefaultTraceIdHeader by
+// default, in both the header and the trailer.
+func WithResponseTrace(trace *ResponseTrace) grpc.CallOption {
+	return responseTraceCallOption{trace: trace}
+}
+
+func filterCallOptions(callOptions []grpc.CallOption) (grpcOptions []grpc.CallOption, traces []*ResponseTrace) {
+	for _, opt := range callOptions {
+		if co, ok := opt.(responseTraceCallOption); ok {
+			traces = append(traces, co.trace)
+		} else {
+			grpcOptions = append(grpcOptions, opt)
+		}
+	}
+	return grpcOptions, traces
+}
+
+// fillResponseTraces fills traces from the first metadata containing the trace ID. It returns false if none does.
+func fillResponseTraces(traces []*ResponseTrace, traceIdHeaderName string, mds ...metadata.MD) bool {
+	for _, md := range mds {
+		traceIds := md.Get(traceIdHeaderName)
+		if len(traceIds) == 0 {
+			continue
+		}
+		sampled := md.Get(traceIdHeaderName + traceSampledSuffix)
+		for _, trace := range traces {
+			trace.TraceId = traceIds[0]
+			trace.Sampled = len(sampled) > 0 && sampled[0] == "true"
+		}
+		return true
+	}
+	return false
+}
+
+// responseTraceMD returns the metadata sending the trace of a server span back to the caller, nil if the tracer
+// doesn't tell its trace ID.
+func responseTraceMD(o *options, serverSpan opentracing.Span) metadata.MD {
+	tags := grpc_ctxtags.NewTags()
+	injectOpentracingIdsToTags(o.traceHeaderName, o.traceFormats, serverSpan, tags)
+	traceId, ok := grpc_ctxtags.GetString(tags, TagTraceId)
+	if !ok || traceId == "" {
+		return nil
+	}
+	md := metadata.Pairs(o.traceIdHeaderName, traceId)
+	if sampled, ok := grpc_ctxtags.GetString(tags, TagSampled); ok {
+		md.Set(o.traceIdHeaderName+traceSampledSuffix, sampled)
+	}
+	return md
+}
+
+// sendResponseTrace sends the trace of a server span back to the caller, according to WithTraceIdInResponse.
+func sendResponseTrace(o *options, serverSpan opentracing.Span, setHeader func(metadata.MD) error, setTrailer func(metadata.MD)) {
+	if o.traceIdLocation == 0 {
+		return
+	}
+	md := responseTraceMD(o, serverSpan)
+	if md == nil {
+		return
+	}
+	switch o.traceIdLocation {
+	case TraceIdInHeader:
+		if err := setHeader(md); err != nil {
+			grpclog.Infof("grpc_opentracing: failed sending the trace ID in the header: %v", err)
+		}
+	case TraceIdInTrailer:
+		setTrailer(md)
+	}
+}
+
+func sendUnaryResponseTrace(ctx context.Context, o *options, serverSpan opentracing.Span) {
+	sendResponseTrace(o, serverSpan,
+		func(md metadata.MD) error { return grpc.SetHeader(ctx, md) },
+		func(md metadata.MD) {
+			if err := grpc.SetTrailer(ctx, md); err != nil {
+				grpclog.Infof("grpc_opentracing: failed sending the trace ID in the trailer: %v", err)
+			}
+		})
+}
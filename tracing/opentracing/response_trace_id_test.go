@@ -0,0 +1,129 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_opentracing_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
+)
+
+func TestResponseTraceSuite(t *testing.T) {
+	for _, tcase := range []struct {
+		name     string
+		location grpc_opentracing.TraceIdLocation
+		header   string
+	}{
+		{name: "Header", location: grpc_opentracing.TraceIdInHeader},
+		{name: "Trailer", location: grpc_opentracing.TraceIdInTrailer, header: "x-request-trace"},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			mockTracer := mocktracer.New()
+			opts := []grpc_opentracing.Option{
+				grpc_opentracing.WithTracer(mockTracer),
+				grpc_opentracing.WithTraceIdInResponse(tcase.location, tcase.header),
+			}
+			header := tcase.header
+			if header == "" {
+				header = grpc_opentracing.DefaultTraceIdHeader
+			}
+			suite.Run(t, &ResponseTraceSuite{
+				InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+					ClientOpts: []grpc.DialOption{
+						grpc.WithUnaryInterceptor(grpc_opentracing.UnaryClientInterceptor(opts...)),
+						grpc.WithStreamInterceptor(grpc_opentracing.StreamClientInterceptor(opts...)),
+					},
+					ServerOpts: []grpc.ServerOption{
+						grpc.UnaryInterceptor(grpc_opentracing.UnaryServerInterceptor(opts...)),
+						grpc.StreamInterceptor(grpc_opentracing.StreamServerInterceptor(opts...)),
+					},
+				},
+				mockTracer: mockTracer,
+				location:   tcase.location,
+				header:     header,
+			})
+		})
+	}
+}
+
+type ResponseTraceSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	mockTracer *mocktracer.MockTracer
+	location   grpc_opentracing.TraceIdLocation
+	header     string
+}
+
+func (s *ResponseTraceSuite) SetupTest() {
+	s.mockTracer.Reset()
+}
+
+// serverTraceId returns the trace ID of the server span of a method.
+func (s *ResponseTraceSuite) serverTraceId(methodName string) string {
+	for _, span := range s.mockTracer.FinishedSpans() {
+		if span.OperationName == methodName && span.Tag(string(ext.SpanKind)) == ext.SpanKindRPCServerEnum {
+			return fmt.Sprint(span.SpanContext.TraceID)
+		}
+	}
+	require.Fail(s.T(), "server span must be there")
+	return ""
+}
+
+func (s *ResponseTraceSuite) TestPing_SendsTraceId() {
+	var trace grpc_opentracing.ResponseTrace
+	var header, trailer metadata.MD
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc_opentracing.WithResponseTrace(&trace), grpc.Header(&header), grpc.Trailer(&trailer))
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+
+	traceId := s.serverTraceId("/mwitkow.testproto.TestService/Ping")
+	assert.Equal(s.T(), grpc_opentracing.ResponseTrace{TraceId: traceId, Sampled: true}, trace, "the trace of the server must be returned")
+	md := header
+	if s.location == grpc_opentracing.TraceIdInTrailer {
+		md = trailer
+	}
+	assert.Equal(s.T(), []string{traceId}, md.Get(s.header), "the trace ID must be in the metadata")
+	assert.Equal(s.T(), []string{"true"}, md.Get(s.header+"-sampled"), "the sampled flag must be in the metadata")
+}
+
+func (s *ResponseTraceSuite) TestPingError_SendsTraceId() {
+	var trace grpc_opentracing.ResponseTrace
+	erroringPing := &pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.Internal)}
+	_, err := s.Client.PingError(s.SimpleCtx(), erroringPing, grpc_opentracing.WithResponseTrace(&trace))
+	require.Error(s.T(), err, "there must be an error returned here")
+	assert.Equal(s.T(), s.serverTraceId("/mwitkow.testproto.TestService/PingError"), trace.TraceId, "failed calls must return the trace too")
+}
+
+func (s *ResponseTraceSuite) TestPingList_SendsTraceId() {
+	var trace grpc_opentracing.ResponseTrace
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing, grpc_opentracing.WithResponseTrace(&trace))
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		if s.location == grpc_opentracing.TraceIdInHeader {
+			assert.NotEmpty(s.T(), trace.TraceId, "the trace must be filled from the first message")
+		}
+	}
+	assert.Equal(s.T(), s.serverTraceId("/mwitkow.testproto.TestService/PingList"), trace.TraceId, "the trace must be filled at the end of the stream")
+}
+
+func (s *ResponseTraceSuite) TestPing_WithoutCallOption() {
+	var header, trailer metadata.MD
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing, grpc.Header(&header), grpc.Trailer(&trailer))
+	require.NoError(s.T(), err, "the call option is not required")
+	assert.NotEmpty(s.T(), append(header.Get(s.header), trailer.Get(s.header)...), "the trace ID must be sent anyway")
+}
@@ -31,7 +31,8 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 		if o.opNameFunc != nil {
 			opName = o.opNameFunc(info.FullMethod)
 		}
-		newCtx, serverSpan := newServerSpanFromInbound(ctx, o.tracer, o.traceHeaderName, opName)
+		newCtx, serverSpan := newServerSpanFromInbound(ctx, o, opName)
+		sendUnaryResponseTrace(ctx, o, serverSpan)
 		if o.unaryRequestHandlerFunc != nil {
 			o.unaryRequestHandlerFunc(serverSpan, req)
 		}
@@ -52,30 +53,58 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 		if o.opNameFunc != nil {
 			opName = o.opNameFunc(info.FullMethod)
 		}
-		newCtx, serverSpan := newServerSpanFromInbound(stream.Context(), o.tracer, o.traceHeaderName, opName)
+		newCtx, serverSpan := newServerSpanFromInbound(stream.Context(), o, opName)
+		sendResponseTrace(o, serverSpan, stream.SetHeader, stream.SetTrailer)
 		wrappedStream := grpc_middleware.WrapServerStream(stream)
 		wrappedStream.WrappedContext = newCtx
-		err := handler(srv, wrappedStream)
+		var err error
+		if events := newMessageEvents(serverSpan, o); events != nil {
+			err = handler(srv, &tracedServerStream{WrappedServerStream: wrappedStream, events: events})
+		} else {
+			err = handler(srv, wrappedStream)
+		}
 		finishServerSpan(newCtx, serverSpan, err)
 		return err
 	}
 }
 
-func newServerSpanFromInbound(ctx context.Context, tracer opentracing.Tracer, traceHeaderName, opName string) (context.Context, opentracing.Span) {
+// tracedServerStream logs the messages of a stream on the server span.
+type tracedServerStream struct {
+	*grpc_middleware.WrappedServerStream
+	events *messageEvents
+}
+
+func (s *tracedServerStream) SendMsg(m interface{}) error {
+	err := s.WrappedServerStream.SendMsg(m)
+	if err == nil {
+		s.events.log(messageSent, m)
+	}
+	return err
+}
+
+func (s *tracedServerStream) RecvMsg(m interface{}) error {
+	err := s.WrappedServerStream.RecvMsg(m)
+	if err == nil {
+		s.events.log(messageReceived, m)
+	}
+	return err
+}
+
+func newServerSpanFromInbound(ctx context.Context, o *options, opName string) (context.Context, opentracing.Span) {
 	md := metautils.ExtractIncoming(ctx)
-	parentSpanContext, err := tracer.Extract(opentracing.HTTPHeaders, metadataTextMap(md))
+	parentSpanContext, err := o.tracer.Extract(opentracing.HTTPHeaders, metadataTextMap(md))
 	if err != nil && err != opentracing.ErrSpanContextNotFound {
 		grpclog.Infof("grpc_opentracing: failed parsing trace information: %v", err)
 	}
 
-	serverSpan := tracer.StartSpan(
+	serverSpan := o.tracer.StartSpan(
 		opName,
 		// this is magical, it attaches the new span to the parent parentSpanContext, and creates an unparented one if empty.
 		ext.RPCServerOption(parentSpanContext),
 		grpcTag,
 	)
 
-	injectOpentracingIdsToTags(traceHeaderName, serverSpan, grpc_ctxtags.Extract(ctx))
+	injectOpentracingIdsToTags(o.traceHeaderName, o.traceFormats, serverSpan, grpc_ctxtags.Extract(ctx))
 	return opentracing.ContextWithSpan(ctx, serverSpan), serverSpan
 }
 
@@ -0,0 +1,112 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_otel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	instrumentationName = "github.com/grpc-ecosystem/go-grpc-middleware/tracing/otel"
+
+	TagTraceId = "trace.traceid"
+	TagSpanId  = "trace.spanid"
+	TagSampled = "trace.sampled"
+)
+
+// rpcAttributes returns the attributes of the OpenTelemetry RPC semantic conventions for a method, e.g.
+// `/mwitkow.testproto.TestService/Ping`.
+func rpcAttributes(fullMethodName string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.RPCSystemKey.String("grpc")}
+	name := strings.TrimPrefix(fullMethodName, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		attrs = append(attrs, semconv.RPCServiceKey.String(name[:i]), semconv.RPCMethodKey.String(name[i+1:]))
+	}
+	return attrs
+}
+
+// injectIdsToTags writes the identifiers of a span to ctxtags.
+func injectIdsToTags(span trace.Span, tags grpc_ctxtags.Tags) {
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return
+	}
+	tags.Set(TagTraceId, sc.TraceID().String())
+	tags.Set(TagSpanId, sc.SpanID().String())
+	tags.Set(TagSampled, fmt.Sprint(sc.IsSampled()))
+}
+
+// setTagsAsAttributes sets the ctxtags on a span, as attributes. Errors are recorded as events rather than attributes.
+func setTagsAsAttributes(span trace.Span, tags grpc_ctxtags.Tags) {
+	for k, v := range tags.Values() {
+		if vErr, ok := v.(error); ok {
+			span.AddEvent(k, trace.WithAttributes(attribute.String("message", vErr.Error())))
+			continue
+		}
+		span.SetAttributes(tagAttribute(k, v))
+	}
+}
+
+// tagAttribute converts the value of a tag to the closest attribute type, falling back to its string form.
+func tagAttribute(k string, v interface{}) attribute.KeyValue {
+	switch v := v.(type) {
+	case string:
+		return attribute.String(k, v)
+	case bool:
+		return attribute.Bool(k, v)
+	case int:
+		return attribute.Int(k, v)
+	case int32:
+		return attribute.Int64(k, int64(v))
+	case int64:
+		return attribute.Int64(k, v)
+	case uint32:
+		return attribute.Int64(k, int64(v))
+	case float32:
+		return attribute.Float64(k, float64(v))
+	case float64:
+		return attribute.Float64(k, v)
+	case time.Duration:
+		return attribute.String(k, v.String())
+	case []string:
+		return attribute.StringSlice(k, v)
+	case []int64:
+		return attribute.Int64Slice(k, v)
+	case fmt.Stringer:
+		return attribute.String(k, v.String())
+	}
+	return attribute.String(k, fmt.Sprint(v))
+}
+
+// setStatus sets the gRPC status code of a span, and marks it as an error according to the OpenTelemetry RPC
+// semantic conventions: every code but OK for clients, and only the codes of server faults for servers.
+func setStatus(span trace.Span, err error, isServer bool) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	if !isServer || isServerFault(code) {
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+}
+
+func isServerFault(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`grpc_otel` adds OpenTelemetry tracing
+
+OpenTelemetry Interceptors
+
+These are both client-side and server-side interceptors for OpenTelemetry, the successor of OpenTracing. They offer the
+same hooks as `grpc_opentracing`: a `FilterFunc` to skip some methods, custom span names and a unary request handler.
+
+For a service that sends out requests and receives requests, you *need* to use both, otherwise downstream requests will
+not have the appropriate requests propagated. The trace context is propagated in the metadata of calls with the W3C
+Trace Context format (`traceparent` and `tracestate`) by default, and with any propagator set by `WithPropagator`.
+
+Spans follow the OpenTelemetry RPC semantic conventions: they have the `rpc.system`, `rpc.service`, `rpc.method` and
+`rpc.grpc.status_code` attributes, and are marked as errors for every failed call on the client side, and only for the
+codes of server faults (e.g. `Internal` or `Unavailable`) on the server side.
+
+All server-side spans get the grpc_ctxtags information as attributes, and the trace and span IDs are set in the tags.
+
+For more information see:
+https://opentelemetry.io/docs/
+https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/trace/semantic_conventions/rpc.md
+
+*/
+package grpc_otel
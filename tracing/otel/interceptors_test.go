@@ -0,0 +1,209 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_otel_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/grpc-ecosystem/go-grpc-middleware/testing"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tracing/otel"
+)
+
+var (
+	goodPing                = &pb_testproto.PingRequest{Value: "something", SleepTimeMs: 9999}
+	unaryRequestHandlerFunc = func(span trace.Span, req interface{}) {
+		span.SetAttributes(attribute.Bool("unary-request-handler", true))
+	}
+)
+
+type tracingAssertService struct {
+	pb_testproto.TestServiceServer
+	T *testing.T
+}
+
+func (s *tracingAssertService) Ping(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
+	assert.True(s.T, trace.SpanContextFromContext(ctx).IsValid(), "handlers must have the span in their context, otherwise propagation will fail")
+	tags := grpc_ctxtags.Extract(ctx)
+	assert.True(s.T, tags.Has(grpc_otel.TagTraceId), "tags must contain traceid")
+	assert.True(s.T, tags.Has(grpc_otel.TagSpanId), "tags must contain spanid")
+	assert.Equal(s.T, "true", tags.Values()[grpc_otel.TagSampled], "sampled must be set to true")
+	return s.TestServiceServer.Ping(ctx, ping)
+}
+
+func (s *tracingAssertService) PingList(ping *pb_testproto.PingRequest, stream pb_testproto.TestService_PingListServer) error {
+	assert.True(s.T, trace.SpanContextFromContext(stream.Context()).IsValid(), "handlers must have the span in their context, otherwise propagation will fail")
+	return s.TestServiceServer.PingList(ping, stream)
+}
+
+func TestOtelSuite(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts := []grpc_otel.Option{
+		grpc_otel.WithTracerProvider(provider),
+		grpc_otel.WithFilterFunc(func(ctx context.Context, fullMethodName string) bool {
+			return fullMethodName != "/mwitkow.testproto.TestService/PingEmpty"
+		}),
+		grpc_otel.WithUnaryRequestHandlerFunc(unaryRequestHandlerFunc),
+	}
+	s := &OtelSuite{
+		recorder: recorder,
+		tracer:   provider.Tracer("test"),
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &tracingAssertService{TestServiceServer: &grpc_testing.TestPingService{T: t}, T: t},
+			ClientOpts: []grpc.DialOption{
+				grpc.WithUnaryInterceptor(grpc_otel.UnaryClientInterceptor(opts...)),
+				grpc.WithStreamInterceptor(grpc_otel.StreamClientInterceptor(opts...)),
+			},
+			ServerOpts: []grpc.ServerOption{
+				grpc_middleware.WithStreamServerChain(
+					grpc_ctxtags.StreamServerInterceptor(grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor)),
+					grpc_otel.StreamServerInterceptor(opts...)),
+				grpc_middleware.WithUnaryServerChain(
+					grpc_ctxtags.UnaryServerInterceptor(grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor)),
+					grpc_otel.UnaryServerInterceptor(opts...)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type OtelSuite struct {
+	*grpc_testing.InterceptorTestSuite
+	recorder *tracetest.SpanRecorder
+	tracer   trace.Tracer
+	// parent is the span of the caller of the tested RPC.
+	parent trace.Span
+}
+
+func (s *OtelSuite) SetupTest() {
+	// Spans of other tests are told apart by their trace.
+	_, s.parent = s.tracer.Start(context.Background(), "/fake/parent/http/request")
+}
+
+func (s *OtelSuite) parentCtx() context.Context {
+	return trace.ContextWithSpan(s.SimpleCtx(), s.parent)
+}
+
+func (s *OtelSuite) spansOf(methodName string) (clientSpan, serverSpan sdktrace.ReadOnlySpan) {
+	for _, span := range s.recorder.Ended() {
+		if span.SpanContext().TraceID() != s.parent.SpanContext().TraceID() || span.Name() != methodName {
+			continue
+		}
+		switch span.SpanKind() {
+		case trace.SpanKindClient:
+			clientSpan = span
+		case trace.SpanKindServer:
+			serverSpan = span
+		}
+	}
+	return clientSpan, serverSpan
+}
+
+func (s *OtelSuite) assertTracesCreated(methodName string) (clientSpan, serverSpan sdktrace.ReadOnlySpan) {
+	clientSpan, serverSpan = s.spansOf(methodName)
+	require.NotNil(s.T(), clientSpan, "client span must be there")
+	require.NotNil(s.T(), serverSpan, "server span must be there")
+	assert.Equal(s.T(), s.parent.SpanContext().SpanID(), clientSpan.Parent().SpanID(), "the client span must be a child of the caller's span")
+	assert.Equal(s.T(), clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID(), "the server span must be a child of the client span")
+	assert.True(s.T(), serverSpan.Parent().IsRemote(), "the server span must have been propagated in metadata")
+	serverAttrs := attributes(serverSpan)
+	assert.Equal(s.T(), "grpc", serverAttrs["rpc.system"], "spans must follow the RPC semantic conventions")
+	assert.Equal(s.T(), "mwitkow.testproto.TestService", serverAttrs["rpc.service"], "spans must follow the RPC semantic conventions")
+	assert.Equal(s.T(), "something", serverAttrs["grpc.request.value"], "grpc_ctxtags must be propagated, in this case ones from request fields")
+	return clientSpan, serverSpan
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return attrs
+}
+
+func (s *OtelSuite) TestPing_PropagatesTraces() {
+	_, err := s.Client.Ping(s.parentCtx(), goodPing)
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	clientSpan, serverSpan := s.assertTracesCreated("mwitkow.testproto.TestService/Ping")
+	assert.Equal(s.T(), "Ping", attributes(clientSpan)["rpc.method"])
+	assert.EqualValues(s.T(), codes.OK, attributes(clientSpan)["rpc.grpc.status_code"])
+	assert.Equal(s.T(), otelcodes.Unset, serverSpan.Status().Code, "successful calls must not be errors")
+	assert.Equal(s.T(), true, attributes(clientSpan)["unary-request-handler"], "the unary request handler must be called")
+}
+
+func (s *OtelSuite) TestPingList_PropagatesTraces() {
+	stream, err := s.Client.PingList(s.parentCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+	}
+	clientSpan, _ := s.assertTracesCreated("mwitkow.testproto.TestService/PingList")
+	assert.Equal(s.T(), otelcodes.Unset, clientSpan.Status().Code, "the end of the stream must not be an error")
+}
+
+func (s *OtelSuite) TestPingList_EndsClientSpanWithTheStream() {
+	stream, err := s.Client.PingList(s.parentCtx(), goodPing)
+	require.NoError(s.T(), err, "should not fail on establishing the stream")
+	var lastRecv time.Time
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(s.T(), err, "reading stream should not fail")
+		lastRecv = time.Now()
+	}
+	clientSpan, _ := s.assertTracesCreated("mwitkow.testproto.TestService/PingList")
+	assert.True(s.T(), clientSpan.EndTime().After(lastRecv), "the client span must end after the last response was received")
+}
+
+func (s *OtelSuite) TestPingError_ClientFault() {
+	erroringPing := &pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.OutOfRange)}
+	_, err := s.Client.PingError(s.parentCtx(), erroringPing)
+	require.Error(s.T(), err, "there must be an error returned here")
+	clientSpan, serverSpan := s.assertTracesCreated("mwitkow.testproto.TestService/PingError")
+	assert.Equal(s.T(), otelcodes.Error, clientSpan.Status().Code, "client span needs to be marked as an error")
+	assert.Equal(s.T(), otelcodes.Unset, serverSpan.Status().Code, "errors of the client must not mark the server span as an error")
+	assert.EqualValues(s.T(), codes.OutOfRange, attributes(serverSpan)["rpc.grpc.status_code"])
+}
+
+func (s *OtelSuite) TestPingError_ServerFault() {
+	erroringPing := &pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.Internal)}
+	_, err := s.Client.PingError(s.parentCtx(), erroringPing)
+	require.Error(s.T(), err, "there must be an error returned here")
+	clientSpan, serverSpan := s.assertTracesCreated("mwitkow.testproto.TestService/PingError")
+	assert.Equal(s.T(), otelcodes.Error, clientSpan.Status().Code, "client span needs to be marked as an error")
+	assert.Equal(s.T(), otelcodes.Error, serverSpan.Status().Code, "server span needs to be marked as an error")
+	require.NotEmpty(s.T(), serverSpan.Events(), "the error must be recorded")
+	assert.Equal(s.T(), "exception", serverSpan.Events()[0].Name)
+}
+
+func (s *OtelSuite) TestPingEmpty_Filtered() {
+	_, err := s.Client.PingEmpty(s.parentCtx(), &pb_testproto.Empty{})
+	require.NoError(s.T(), err, "there must be not be an error on a successful call")
+	clientSpan, serverSpan := s.spansOf("mwitkow.testproto.TestService/PingEmpty")
+	assert.Nil(s.T(), clientSpan, "filtered out calls must not be traced")
+	assert.Nil(s.T(), serverSpan, "filtered out calls must not be traced")
+}
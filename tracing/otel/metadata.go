@@ -0,0 +1,36 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_otel
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// metadataCarrier extends a metadata.MD to be an OpenTelemetry propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+// Get returns the first value of a key.
+func (m metadataCarrier) Get(key string) string {
+	vals := metadata.MD(m).Get(key)
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// Set overrides the values of a key, as the propagated headers are never appended.
+func (m metadataCarrier) Set(key, val string) {
+	m[strings.ToLower(key)] = []string{val}
+}
+
+// Keys lists the keys of the metadata.
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
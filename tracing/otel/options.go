@@ -0,0 +1,105 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_otel
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	defaultOptions = &options{
+		filterOutFunc:  nil,
+		tracerProvider: nil,
+	}
+)
+
+// FilterFunc allows users to provide a function that filters out certain methods from being traced.
+//
+// If it returns false, the given request will not be traced.
+type FilterFunc func(ctx context.Context, fullMethodName string) bool
+
+// UnaryRequestHandlerFunc is a custom request handler
+type UnaryRequestHandlerFunc func(span trace.Span, req interface{})
+
+// OpNameFunc is a func that allows custom span names instead of the gRPC method.
+type OpNameFunc func(method string) string
+
+type options struct {
+	filterOutFunc           FilterFunc
+	tracerProvider          trace.TracerProvider
+	propagator              propagation.TextMapPropagator
+	unaryRequestHandlerFunc UnaryRequestHandlerFunc
+	opNameFunc              OpNameFunc
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	if optCopy.tracerProvider == nil {
+		optCopy.tracerProvider = otel.GetTracerProvider()
+	}
+	if optCopy.propagator == nil {
+		optCopy.propagator = propagation.TraceContext{}
+	}
+	return optCopy
+}
+
+func (o *options) tracer() trace.Tracer {
+	return o.tracerProvider.Tracer(instrumentationName)
+}
+
+func (o *options) spanName(fullMethodName string) string {
+	if o.opNameFunc != nil {
+		return o.opNameFunc(fullMethodName)
+	}
+	return strings.TrimPrefix(fullMethodName, "/")
+}
+
+type Option func(*options)
+
+// WithFilterFunc customizes the function used for deciding whether a given call is traced or not.
+func WithFilterFunc(f FilterFunc) Option {
+	return func(o *options) {
+		o.filterOutFunc = f
+	}
+}
+
+// WithTracerProvider sets a custom tracer provider to be used for this middleware, otherwise the global one of
+// otel.GetTracerProvider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithPropagator sets how the trace context is propagated in the metadata of calls.
+// Default one is the W3C Trace Context (`traceparent` and `tracestate`).
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = p
+	}
+}
+
+// WithUnaryRequestHandlerFunc sets a custom handler for the request
+func WithUnaryRequestHandlerFunc(f UnaryRequestHandlerFunc) Option {
+	return func(o *options) {
+		o.unaryRequestHandlerFunc = f
+	}
+}
+
+// WithOpName customizes the span name, which is `package.Service/Method` by default, as in the OpenTelemetry RPC
+// semantic conventions.
+func WithOpName(f OpNameFunc) Option {
+	return func(o *options) {
+		o.opNameFunc = f
+	}
+}
@@ -9,20 +9,96 @@ Implements common backoff features.
 package backoffutils
 
 import (
+	"math"
 	"math/rand"
 	"time"
 )
 
+// RandFunc returns a pseudo-random number in [0.0,1.0). It allows the jittered backoffs to be deterministic
+// in tests. A nil RandFunc uses `math/rand`.
+type RandFunc func() float64
+
+func (f RandFunc) float64() float64 {
+	if f == nil {
+		return rand.Float64()
+	}
+	return f()
+}
+
 // JitterUp adds random jitter to the duration.
 //
 // This adds or subtracts time from the duration within a given jitter fraction.
 // For example for 10s and jitter 0.1, it will return a time within [9s, 11s])
 func JitterUp(duration time.Duration, jitter float64) time.Duration {
 	multiplier := jitter * (rand.Float64()*2 - 1)
-	return time.Duration(float64(duration) * (1 + multiplier))
+	return fromFloat(float64(duration) * (1 + multiplier))
 }
 
 // ExponentBase2 computes 2^(a-1) where a >= 1. If a is 0, the result is 0.
 func ExponentBase2(a uint) uint {
 	return (1 << a) >> 1
 }
+
+// ExponentialCapped computes base * 2^(attempt-1), capped at maxDuration. If attempt is 0, the result is 0.
+//
+// A maxDuration <= 0 caps the result at the largest time.Duration instead. The computation never overflows,
+// however large the attempt is.
+func ExponentialCapped(base time.Duration, maxDuration time.Duration, attempt uint) time.Duration {
+	if maxDuration <= 0 {
+		maxDuration = math.MaxInt64
+	}
+	if attempt == 0 || base <= 0 {
+		return 0
+	}
+	shift := attempt - 1
+	if shift >= 63 || base > maxDuration>>shift {
+		return maxDuration
+	}
+	return base << shift
+}
+
+// FullJitter returns a random duration between 0 and ExponentialCapped(base, maxDuration, attempt).
+//
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/.
+func FullJitter(base time.Duration, maxDuration time.Duration, attempt uint, rnd RandFunc) time.Duration {
+	return fromFloat(rnd.float64() * float64(ExponentialCapped(base, maxDuration, attempt)))
+}
+
+// EqualJitter returns a random duration between half of ExponentialCapped(base, maxDuration, attempt) and
+// all of it.
+//
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/.
+func EqualJitter(base time.Duration, maxDuration time.Duration, attempt uint, rnd RandFunc) time.Duration {
+	half := ExponentialCapped(base, maxDuration, attempt) / 2
+	return half + fromFloat(rnd.float64()*float64(half))
+}
+
+// DecorrelatedJitter returns the duration following previous, which is a random duration between base and
+// three times previous, capped at maxDuration. The first previous duration should be base.
+//
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/.
+func DecorrelatedJitter(base time.Duration, maxDuration time.Duration, previous time.Duration, rnd RandFunc) time.Duration {
+	if maxDuration <= 0 {
+		maxDuration = math.MaxInt64
+	}
+	upper := 3 * float64(previous)
+	if upper < float64(base) {
+		upper = float64(base)
+	}
+	next := fromFloat(float64(base) + rnd.float64()*(upper-float64(base)))
+	if next > maxDuration {
+		return maxDuration
+	}
+	return next
+}
+
+// fromFloat converts a float64 number of nanoseconds to a time.Duration, saturating instead of overflowing.
+func fromFloat(d float64) time.Duration {
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if d <= math.MinInt64 {
+		return math.MinInt64
+	}
+	return time.Duration(d)
+}
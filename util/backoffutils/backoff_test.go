@@ -4,6 +4,7 @@
 package backoffutils_test
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -46,3 +47,47 @@ func TestJitterUp(t *testing.T) {
 	assert.True(t, highCount != 0, "at least one sample should reach to >%s", high)
 	assert.True(t, lowCount != 0, "at least one sample should to <%s", low)
 }
+
+func fixedRand(v float64) backoffutils.RandFunc {
+	return func() float64 { return v }
+}
+
+func TestExponentialCapped(t *testing.T) {
+	assert.Equal(t, time.Duration(0), backoffutils.ExponentialCapped(100*time.Millisecond, time.Second, 0))
+	assert.Equal(t, 100*time.Millisecond, backoffutils.ExponentialCapped(100*time.Millisecond, time.Second, 1))
+	assert.Equal(t, 800*time.Millisecond, backoffutils.ExponentialCapped(100*time.Millisecond, time.Second, 4))
+	assert.Equal(t, time.Second, backoffutils.ExponentialCapped(100*time.Millisecond, time.Second, 5))
+	for _, attempt := range []uint{40, 63, 64, 65, 1000, math.MaxUint32} {
+		assert.Equal(t, time.Second, backoffutils.ExponentialCapped(100*time.Millisecond, time.Second, attempt), "attempt %d must be capped", attempt)
+		assert.Equal(t, time.Duration(math.MaxInt64), backoffutils.ExponentialCapped(100*time.Millisecond, 0, attempt), "attempt %d must not overflow", attempt)
+	}
+}
+
+func TestFullJitter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), backoffutils.FullJitter(100*time.Millisecond, time.Second, 3, fixedRand(0)))
+	assert.Equal(t, 200*time.Millisecond, backoffutils.FullJitter(100*time.Millisecond, time.Second, 3, fixedRand(0.5)))
+	assert.Equal(t, 500*time.Millisecond, backoffutils.FullJitter(100*time.Millisecond, time.Second, 100, fixedRand(0.5)))
+	for i := 0; i < 1000; i++ {
+		out := backoffutils.FullJitter(100*time.Millisecond, time.Second, 3, nil)
+		assert.True(t, out >= 0 && out <= 400*time.Millisecond, "value %s must be within [0, 400ms]", out)
+	}
+}
+
+func TestEqualJitter(t *testing.T) {
+	assert.Equal(t, 200*time.Millisecond, backoffutils.EqualJitter(100*time.Millisecond, time.Second, 3, fixedRand(0)))
+	assert.Equal(t, 300*time.Millisecond, backoffutils.EqualJitter(100*time.Millisecond, time.Second, 3, fixedRand(0.5)))
+	assert.Equal(t, time.Duration(math.MaxInt64/2), backoffutils.EqualJitter(time.Second, 0, 100, fixedRand(0)))
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, backoffutils.DecorrelatedJitter(100*time.Millisecond, time.Second, 100*time.Millisecond, fixedRand(0)))
+	assert.Equal(t, 200*time.Millisecond, backoffutils.DecorrelatedJitter(100*time.Millisecond, time.Second, 100*time.Millisecond, fixedRand(0.5)))
+	assert.Equal(t, time.Second, backoffutils.DecorrelatedJitter(100*time.Millisecond, time.Second, 900*time.Millisecond, fixedRand(0.5)))
+	assert.Equal(t, time.Duration(math.MaxInt64), backoffutils.DecorrelatedJitter(100*time.Millisecond, 0, math.MaxInt64, fixedRand(0.9)))
+}
+
+func TestJitterUp_DoesNotOverflow(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		assert.True(t, backoffutils.JitterUp(math.MaxInt64, 0.5) > 0, "jitter must saturate instead of overflowing")
+	}
+}
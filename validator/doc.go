@@ -12,6 +12,37 @@ includes the single request of the `Unary` calls, as well as each message of the
 In case of a validation failure, an `InvalidArgument` gRPC status is returned, along with a
 description of the validation failure.
 
+Messages are validated against all their rules when they support it (`ValidateAll()` or `Validate(true)`
+of https://github.com/envoyproxy/protoc-gen-validate). When the error describes the fields breaking the
+rules, the status carries a `google.rpc.BadRequest` detail with a violation per field, named by its proto
+path (e.g. `inner.some_integer` or `items[2].sku`), so that clients can point at the invalid fields.
+
+The `WithFailFast` option stops the validation at the first broken rule instead. `WithSkipMethods` and
+`WithSkipMessages` disable the validation of some methods or message types. The status returned for
+invalid requests can be customized with `WithCode` and `WithMessageFunc` (e.g. with a `MessageTemplate`,
+or to localize the message according to the metadata of the call), and `WithErrorTransform` has the last
+word on the errors returned for invalid messages.
+
+On the client side, `UnaryClientInterceptor` and `StreamClientInterceptor` validate the messages sent
+before they reach the server. With the `WithResponseValidation` option, responses are validated too:
+servers replace invalid responses by an `Internal` error (and log them, as they are a bug of the
+handler), and clients reject invalid responses received with an `Internal` error.
+
+Messages without generated `Validate` methods, or needing more rules than the generated ones, can be
+validated by the functions of a `Registry` passed with the `WithRegistry` option. Functions are registered
+per message type or per method, and run after the generated validator. `Rules` and `ParseRules` build
+such functions from rules over the proto field paths of a message (required, numeric ranges, lengths and
+regular expressions), written in Go or in JSON configuration, and report the fields breaking them as
+`Violations`:
+
+	rules, err := grpc_validator.Rules(&pb.CreateUserRequest{},
+		grpc_validator.Rule{Path: "user.email", Required: true, Pattern: "^[^@]+@[^@]+$"},
+		grpc_validator.Rule{Path: "user.age", Min: grpc_validator.Bound(18)},
+	)
+	registry := grpc_validator.NewRegistry()
+	registry.RegisterType(&pb.CreateUserRequest{}, rules)
+	server := grpc.NewServer(grpc.UnaryInterceptor(grpc_validator.UnaryServerInterceptor(grpc_validator.WithRegistry(registry))))
+
 While it is generic, it was intended to be used with https://github.com/mwitkow/go-proto-validators,
 a Go protocol buffers codegen plugin that creates the `Validate` methods (including nested messages)
 based on declarative options in the `.proto` files themselves. For example:
@@ -0,0 +1,122 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_validator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// The error returned by protoc-gen-validate when validating all the rules of a message.
+type multiError interface {
+	AllErrors() []error
+}
+
+// The error returned by protoc-gen-validate for a field that breaks a rule, named by its Go name (e.g. `SomeInteger`
+// or `Items[2]`). Its cause is the error of the embedded message for fields that are messages themselves.
+type fieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// invalidRequest converts the validation error of a request into a status, `InvalidArgument` unless WithCode says
+// otherwise. When the error describes the fields that break the rules, the status carries a `google.rpc.BadRequest`
+// detail listing them.
+func invalidRequest(ctx context.Context, fullMethod string, req interface{}, err error, o *options) error {
+	msg := err.Error()
+	if o.messageFunc != nil {
+		msg = o.messageFunc(ctx, fullMethod, err)
+	}
+	return transformError(ctx, fullMethod, withFieldViolations(status.New(o.code, msg), req, err), o)
+}
+
+// withFieldViolations returns the error of a status, carrying the fields of a validation error of a message if it
+// describes them.
+func withFieldViolations(st *status.Status, msg interface{}, err error) error {
+	var t reflect.Type
+	if _, ok := msg.(proto.Message); ok {
+		t = messageType(reflect.TypeOf(msg))
+	}
+	violations := fieldViolations(t, "", err, nil)
+	if len(violations) == 0 {
+		return st.Err()
+	}
+	if withDetails, detailsErr := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); detailsErr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// fieldViolations flattens the errors of protoc-gen-validate for a message struct into field violations, the fields
+// being named by their proto path (e.g. `inner.some_integer` or `items[2].sku`). Other errors are ignored.
+func fieldViolations(t reflect.Type, prefix string, err error, violations []*errdetails.BadRequest_FieldViolation) []*errdetails.BadRequest_FieldViolation {
+	switch e := err.(type) {
+	case multiError:
+		for _, err := range e.AllErrors() {
+			violations = fieldViolations(t, prefix, err, violations)
+		}
+	case fieldError:
+		name, fieldType := protoFieldName(t, e.Field())
+		field := prefix + name
+		description := e.Reason()
+		if cause := e.Cause(); cause != nil {
+			if nested := fieldViolations(fieldType, field+".", cause, nil); len(nested) > 0 {
+				return append(violations, nested...)
+			}
+			description += ": " + cause.Error()
+		}
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+	}
+	return violations
+}
+
+// protoFieldName maps the Go name of a field of a message struct, with the index or key of repeated and map fields
+// if any, to its proto name. It also returns the message struct of the field, or of its elements, if it has one.
+// Names that aren't the Go name of a field, e.g. the proto paths of `Violations`, are kept as they are.
+func protoFieldName(t reflect.Type, goName string) (string, reflect.Type) {
+	if t == nil {
+		return goName, nil
+	}
+	name, index := goName, ""
+	if i := strings.IndexByte(goName, '['); i >= 0 {
+		name, index = goName[:i], goName[i:]
+	}
+	props := proto.GetProperties(t)
+	for i, p := range props.Prop {
+		if p.Tag > 0 && p.Name == name {
+			return p.OrigName + index, messageType(t.Field(i).Type)
+		}
+	}
+	for _, oneof := range props.OneofTypes {
+		if oneof.Prop.Name == name {
+			return oneof.Prop.OrigName + index, messageType(oneof.Type.Elem().Field(0).Type)
+		}
+	}
+	return goName, nil
+}
+
+// messageType returns the message struct of a field, or of the elements of repeated and map fields, if any.
+func messageType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		return t.Elem()
+	}
+	return nil
+}
+
+// transformError applies the ErrorTransformFunc of WithErrorTransform, if any.
+func transformError(ctx context.Context, fullMethod string, err error, o *options) error {
+	if o.errorTransform != nil {
+		return o.errorTransform(ctx, fullMethod, err)
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_validator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	defaultOptions = &options{
+		validateResponses: false,
+		code:              codes.InvalidArgument,
+	}
+)
+
+type options struct {
+	validateResponses bool
+	registry          *Registry
+	failFast          bool
+	skipMethods       map[string]bool
+	skipTypes         map[reflect.Type]bool
+	code              codes.Code
+	messageFunc       MessageFunc
+	errorTransform    ErrorTransformFunc
+}
+
+// skip tells whether the validation of a message is disabled by WithSkipMethods or WithSkipMessages.
+func (o *options) skip(fullMethod string, msg interface{}) bool {
+	return o.skipMethods[fullMethod] || o.skipTypes[reflect.TypeOf(msg)]
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+// Option configures the validator interceptors.
+type Option func(*options)
+
+// MessageFunc builds the message of the status returned for an invalid request from the validation error, e.g.
+// to localize it according to the metadata of the call.
+type MessageFunc func(ctx context.Context, fullMethod string, err error) string
+
+// ErrorTransformFunc transforms the error returned for an invalid message, before it reaches the caller.
+type ErrorTransformFunc func(ctx context.Context, fullMethod string, err error) error
+
+// WithResponseValidation also validates the responses of calls.
+//
+// On the server, invalid responses are a bug of the handler: they are logged and replaced by an `Internal` error
+// before they leave. On the client, invalid responses received from the server are returned as an `Internal` error.
+func WithResponseValidation() Option {
+	return func(o *options) {
+		o.validateResponses = true
+	}
+}
+
+// WithRegistry validates messages with the functions of a Registry as well as with their generated `Validate` methods.
+func WithRegistry(r *Registry) Option {
+	return func(o *options) {
+		o.registry = r
+	}
+}
+
+// WithFailFast stops the validation of a message at the first broken rule, instead of reporting all of them.
+//
+// The generated validators are called with `Validate(false)` (or the legacy `Validate()`) rather than
+// `ValidateAll()`, and the functions of the registry stop at the first failing one.
+func WithFailFast() Option {
+	return func(o *options) {
+		o.failFast = true
+	}
+}
+
+// WithSkipMethods disables the validation of the requests and responses of the given methods, e.g.
+// `/mwitkow.testproto.TestService/Ping`.
+func WithSkipMethods(fullMethods ...string) Option {
+	return func(o *options) {
+		skipMethods := make(map[string]bool, len(o.skipMethods)+len(fullMethods))
+		for m := range o.skipMethods {
+			skipMethods[m] = true
+		}
+		for _, m := range fullMethods {
+			skipMethods[m] = true
+		}
+		o.skipMethods = skipMethods
+	}
+}
+
+// WithSkipMessages disables the validation of the messages of the same types as msgs, e.g. `&pb.PingRequest{}`.
+func WithSkipMessages(msgs ...interface{}) Option {
+	return func(o *options) {
+		skipTypes := make(map[reflect.Type]bool, len(o.skipTypes)+len(msgs))
+		for t := range o.skipTypes {
+			skipTypes[t] = true
+		}
+		for _, msg := range msgs {
+			skipTypes[reflect.TypeOf(msg)] = true
+		}
+		o.skipTypes = skipTypes
+	}
+}
+
+// WithCode sets the code of the status returned for invalid requests, `InvalidArgument` by default.
+func WithCode(code codes.Code) Option {
+	return func(o *options) {
+		o.code = code
+	}
+}
+
+// WithMessageFunc sets how the message of the status returned for invalid requests is built. By default it is
+// the validation error itself.
+func WithMessageFunc(f MessageFunc) Option {
+	return func(o *options) {
+		o.messageFunc = f
+	}
+}
+
+// MessageTemplate returns a MessageFunc that fills a template, in which `{method}` is replaced by the full method
+// name and `{error}` by the validation error, e.g. `invalid request to {method}: {error}`.
+func MessageTemplate(template string) MessageFunc {
+	return func(ctx context.Context, fullMethod string, err error) string {
+		return strings.NewReplacer("{method}", fullMethod, "{error}", err.Error()).Replace(template)
+	}
+}
+
+// WithErrorTransform transforms the errors returned for invalid messages, requests and responses alike, e.g. to
+// add details to their status. It is called with the status error built by the interceptors.
+func WithErrorTransform(f ErrorTransformFunc) Option {
+	return func(o *options) {
+		o.errorTransform = f
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_validator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ValidateFunc validates a message. The error it returns is the reason the message is invalid, ideally
+// `Violations` so that the invalid fields are reported to the client.
+type ValidateFunc func(ctx context.Context, msg interface{}) error
+
+// Registry holds validation functions for messages that don't have generated `Validate` methods, or that need
+// more rules than the generated ones.
+//
+// Functions are registered either for a message type, in which case they validate the message wherever it is
+// sent or received, or for the full name of a method (e.g. `/mwitkow.testproto.TestService/Ping`), in which case
+// they validate its requests only. A message is valid when its generated `Validate` method and all its
+// registered functions accept it.
+type Registry struct {
+	mu      sync.RWMutex
+	types   map[reflect.Type][]ValidateFunc
+	methods map[string][]ValidateFunc
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		types:   make(map[reflect.Type][]ValidateFunc),
+		methods: make(map[string][]ValidateFunc),
+	}
+}
+
+// RegisterType registers a function validating the messages of the same type as msg, e.g. `&pb.PingRequest{}`.
+func (r *Registry) RegisterType(msg interface{}, f ValidateFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := reflect.TypeOf(msg)
+	r.types[t] = append(r.types[t], f)
+}
+
+// RegisterMethod registers a function validating the requests of a method.
+func (r *Registry) RegisterMethod(fullMethod string, f ValidateFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods[fullMethod] = append(r.methods[fullMethod], f)
+}
+
+// funcs returns the functions validating a message, including the ones of the method if it is a request.
+func (r *Registry) funcs(fullMethod string, msg interface{}, isRequest bool) []ValidateFunc {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	funcs := r.types[reflect.TypeOf(msg)]
+	if isRequest {
+		funcs = append(funcs[:len(funcs):len(funcs)], r.methods[fullMethod]...)
+	}
+	return funcs
+}
+
+// validationErrors is the combination of the errors of the generated validator and of the registered functions.
+type validationErrors []error
+
+func (e validationErrors) AllErrors() []error {
+	return e
+}
+
+func (e validationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Violation is a field breaking a validation rule.
+type Violation struct {
+	// Path is the path of the field, e.g. `user.email`.
+	Path        string
+	Description string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("invalid %s: %s", v.Path, v.Description)
+}
+
+// Field, Reason and Cause describe the violation in the same way as the errors of protoc-gen-validate do.
+func (v Violation) Field() string  { return v.Path }
+func (v Violation) Reason() string { return v.Description }
+func (v Violation) Cause() error   { return nil }
+
+// Violations are the fields of a message breaking validation rules.
+type Violations []Violation
+
+func (v Violations) AllErrors() []error {
+	errs := make([]error, len(v))
+	for i, violation := range v {
+		errs[i] = violation
+	}
+	return errs
+}
+
+func (v Violations) Error() string {
+	return validationErrors(v.AllErrors()).Error()
+}
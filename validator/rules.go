@@ -0,0 +1,244 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Rule is a constraint on a field of a message, identified by the path of its proto field names, e.g. `user.email`.
+//
+// Rules can be written in Go, or loaded from a JSON configuration with ParseRules.
+type Rule struct {
+	Path string `json:"path"`
+	// Required rejects fields with the zero value: empty strings, bytes, repeated fields and maps, zero numbers,
+	// unset messages and unset oneof fields.
+	Required bool `json:"required,omitempty"`
+	// Min and Max bound numeric fields, inclusively.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// MinLen and MaxLen bound the number of characters of strings, of bytes, or of elements of repeated fields
+	// and maps, inclusively.
+	MinLen *int `json:"minLen,omitempty"`
+	MaxLen *int `json:"maxLen,omitempty"`
+	// Pattern is a regular expression (RE2 syntax) that string fields must match.
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// Bound returns a pointer to v, to set the Min and Max of a Rule.
+func Bound(v float64) *float64 {
+	return &v
+}
+
+// Length returns a pointer to n, to set the MinLen and MaxLen of a Rule.
+func Length(n int) *int {
+	return &n
+}
+
+// Rules returns a ValidateFunc that checks the rules on messages of the same type as msg, e.g. `&pb.PingRequest{}`,
+// and returns the fields breaking them as `Violations`.
+//
+// It returns an error if a path isn't a field of the message, or if a rule doesn't apply to the type of its field.
+func Rules(msg interface{}, rules ...Rule) (ValidateFunc, error) {
+	t := reflect.TypeOf(msg)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("grpc_validator: rules apply to protobuf messages, got %T", msg)
+	}
+	compiled := make([]*compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileRule(t.Elem(), rule)
+		if err != nil {
+			return nil, fmt.Errorf("grpc_validator: invalid rule for %q of %T: %v", rule.Path, msg, err)
+		}
+		compiled = append(compiled, c)
+	}
+	return func(ctx context.Context, msg interface{}) error {
+		v := reflect.ValueOf(msg)
+		if !v.IsValid() || v.Type() != t || v.IsNil() {
+			return nil
+		}
+		var violations Violations
+		for _, c := range compiled {
+			violations = c.check(v.Elem(), violations)
+		}
+		if len(violations) == 0 {
+			return nil
+		}
+		return violations
+	}, nil
+}
+
+// ParseRules builds the ValidateFunc of rules written in JSON, e.g.:
+//
+//	[
+//	  {"path": "user.email", "required": true, "pattern": "^[^@]+@[^@]+$"},
+//	  {"path": "quantity", "min": 1, "max": 100},
+//	  {"path": "tags", "maxLen": 10}
+//	]
+func ParseRules(msg interface{}, rulesJSON []byte) (ValidateFunc, error) {
+	var rules []Rule
+	if err := json.Unmarshal(rulesJSON, &rules); err != nil {
+		return nil, fmt.Errorf("grpc_validator: failed parsing rules: %v", err)
+	}
+	return Rules(msg, rules...)
+}
+
+// fieldStep locates a field in a message struct.
+type fieldStep struct {
+	// index is the index of the field in the struct, or of the oneof holding it if oneofType is set.
+	index     int
+	oneofType reflect.Type
+}
+
+type compiledRule struct {
+	Rule
+	steps   []fieldStep
+	pattern *regexp.Regexp
+}
+
+func compileRule(t reflect.Type, rule Rule) (*compiledRule, error) {
+	c := &compiledRule{Rule: rule}
+	names := strings.Split(rule.Path, ".")
+	var fieldType reflect.Type
+	for i, name := range names {
+		step, goType, ok := protoField(t, name)
+		if !ok {
+			return nil, fmt.Errorf("no field %q in %v", name, t)
+		}
+		c.steps = append(c.steps, step)
+		if i == len(names)-1 {
+			fieldType = goType
+			break
+		}
+		if goType.Kind() != reflect.Ptr || goType.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("field %q isn't a message", name)
+		}
+		t = goType.Elem()
+	}
+	if (rule.Min != nil || rule.Max != nil) && !isNumber(fieldType.Kind()) {
+		return nil, fmt.Errorf("min and max apply to numbers, not %v", fieldType)
+	}
+	switch fieldType.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+	default:
+		if rule.MinLen != nil || rule.MaxLen != nil {
+			return nil, fmt.Errorf("minLen and maxLen apply to strings, bytes, repeated fields and maps, not %v", fieldType)
+		}
+	}
+	if rule.Pattern != "" {
+		if fieldType.Kind() != reflect.String {
+			return nil, fmt.Errorf("pattern applies to strings, not %v", fieldType)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		c.pattern = re
+	}
+	return c, nil
+}
+
+// protoField finds a field of a message struct by its proto name, including the fields of oneofs.
+func protoField(t reflect.Type, name string) (fieldStep, reflect.Type, bool) {
+	props := proto.GetProperties(t)
+	for i, p := range props.Prop {
+		if p.Tag > 0 && p.OrigName == name {
+			return fieldStep{index: i}, t.Field(i).Type, true
+		}
+	}
+	if oneof, ok := props.OneofTypes[name]; ok {
+		return fieldStep{index: oneof.Field, oneofType: oneof.Type}, oneof.Type.Elem().Field(0).Type, true
+	}
+	return fieldStep{}, nil, false
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+// resolve returns the field of the rule in a message struct, or false if a message on its path or its oneof is unset.
+func (c *compiledRule) resolve(v reflect.Value) (reflect.Value, bool) {
+	for i, step := range c.steps {
+		field := v.Field(step.index)
+		if step.oneofType != nil {
+			if field.IsNil() || field.Elem().Type() != step.oneofType {
+				return reflect.Value{}, false
+			}
+			field = field.Elem().Elem().Field(0)
+		}
+		if i == len(c.steps)-1 {
+			return field, true
+		}
+		if field.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = field.Elem()
+	}
+	return reflect.Value{}, false
+}
+
+func (c *compiledRule) check(v reflect.Value, violations Violations) Violations {
+	field, ok := c.resolve(v)
+	if !ok {
+		if c.Required {
+			violations = append(violations, Violation{Path: c.Path, Description: "value is required"})
+		}
+		return violations
+	}
+	if c.Required && isZero(field) {
+		violations = append(violations, Violation{Path: c.Path, Description: "value is required"})
+	}
+	if c.Min != nil || c.Max != nil {
+		n := toFloat(field)
+		if c.Min != nil && n < *c.Min {
+			violations = append(violations, Violation{Path: c.Path, Description: fmt.Sprintf("value must be greater than or equal to %v", *c.Min)})
+		}
+		if c.Max != nil && n > *c.Max {
+			violations = append(violations, Violation{Path: c.Path, Description: fmt.Sprintf("value must be less than or equal to %v", *c.Max)})
+		}
+	}
+	if c.MinLen != nil || c.MaxLen != nil {
+		n := field.Len()
+		if field.Kind() == reflect.String {
+			n = utf8.RuneCountInString(field.String())
+		}
+		if c.MinLen != nil && n < *c.MinLen {
+			violations = append(violations, Violation{Path: c.Path, Description: fmt.Sprintf("value length must be at least %d", *c.MinLen)})
+		}
+		if c.MaxLen != nil && n > *c.MaxLen {
+			violations = append(violations, Violation{Path: c.Path, Description: fmt.Sprintf("value length must be at most %d", *c.MaxLen)})
+		}
+	}
+	if c.pattern != nil && !c.pattern.MatchString(field.String()) {
+		violations = append(violations, Violation{Path: c.Path, Description: fmt.Sprintf("value does not match regex pattern %q", c.Pattern)})
+	}
+	return violations
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return float64(v.Int())
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uintptr:
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
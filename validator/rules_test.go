@@ -0,0 +1,106 @@
+// Copyright 2016 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package grpc_validator
+
+import (
+	"context"
+	"testing"
+
+	pb_logfield "github.com/grpc-ecosystem/go-grpc-middleware/testing/logfieldtestproto"
+	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules_Violations(t *testing.T) {
+	validate, err := Rules(&pb_logfield.OrderRequest{},
+		Rule{Path: "user.id", Required: true, Pattern: "^u-[0-9]+$"},
+		Rule{Path: "user.name", MaxLen: Length(3)},
+		Rule{Path: "amount", Min: Bound(1), Max: Bound(100)},
+		Rule{Path: "tags", MinLen: Length(1)},
+		Rule{Path: "card_id", Required: true},
+		Rule{Path: "status", Required: true},
+	)
+	require.NoError(t, err)
+
+	err = validate(context.Background(), &pb_logfield.OrderRequest{
+		User:   &pb_logfield.User{Id: "x-1", Name: "Zoë!"},
+		Amount: 101,
+	})
+	assert.Equal(t, Violations{
+		{Path: "user.id", Description: `value does not match regex pattern "^u-[0-9]+$"`},
+		{Path: "user.name", Description: "value length must be at most 3"},
+		{Path: "amount", Description: "value must be less than or equal to 100"},
+		{Path: "tags", Description: "value length must be at least 1"},
+		{Path: "card_id", Description: "value is required"},
+		{Path: "status", Description: "value is required"},
+	}, err)
+
+	err = validate(context.Background(), &pb_logfield.OrderRequest{
+		User:    &pb_logfield.User{Id: "u-1", Name: "Zoë"},
+		Amount:  1,
+		Tags:    []string{"new"},
+		Payment: &pb_logfield.OrderRequest_CardId{CardId: "card"},
+		Status:  pb_logfield.Status_PENDING,
+	})
+	assert.NoError(t, err, "messages following the rules must be valid")
+}
+
+func TestRules_UnsetParents(t *testing.T) {
+	validate, err := Rules(&pb_logfield.OrderRequest{},
+		Rule{Path: "user.id", Required: true},
+		Rule{Path: "payer.name", MinLen: Length(2)},
+	)
+	require.NoError(t, err)
+
+	err = validate(context.Background(), &pb_logfield.OrderRequest{Payment: &pb_logfield.OrderRequest_CardId{CardId: "card"}})
+	assert.Equal(t, Violations{{Path: "user.id", Description: "value is required"}}, err,
+		"only required fields must be reported when a message on their path is unset")
+}
+
+func TestRules_IgnoresOtherTypes(t *testing.T) {
+	validate, err := Rules(&pb_logfield.OrderRequest{}, Rule{Path: "amount", Min: Bound(1)})
+	require.NoError(t, err)
+	assert.NoError(t, validate(context.Background(), &pb_logfield.User{}))
+	assert.NoError(t, validate(context.Background(), (*pb_logfield.OrderRequest)(nil)))
+	assert.NoError(t, validate(context.Background(), nil))
+}
+
+func TestRules_InvalidRules(t *testing.T) {
+	for _, tcase := range []struct {
+		name string
+		msg  interface{}
+		rule Rule
+	}{
+		{name: "not a message", msg: pb_logfield.User{}, rule: Rule{Path: "id"}},
+		{name: "unknown field", msg: &pb_logfield.OrderRequest{}, rule: Rule{Path: "nope"}},
+		{name: "go field name", msg: &pb_logfield.OrderRequest{}, rule: Rule{Path: "CardId"}},
+		{name: "path through a scalar", msg: &pb_logfield.OrderRequest{}, rule: Rule{Path: "amount.value"}},
+		{name: "path through a repeated field", msg: &pb_logfield.OrderRequest{}, rule: Rule{Path: "items.sku"}},
+		{name: "range of a string", msg: &pb_logfield.OrderRequest{}, rule: Rule{Path: "secret", Min: Bound(1)}},
+		{name: "length of a number", msg: &pb_logfield.OrderRequest{}, rule: Rule{Path: "amount", MaxLen: Length(1)}},
+		{name: "pattern of a list", msg: &pb_logfield.OrderRequest{}, rule: Rule{Path: "tags", Pattern: "a"}},
+		{name: "bad pattern", msg: &pb_logfield.OrderRequest{}, rule: Rule{Path: "secret", Pattern: "("}},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			_, err := Rules(tcase.msg, tcase.rule)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	validate, err := ParseRules(&pb_testproto.PingRequest{}, []byte(`[
+		{"path": "value", "required": true, "maxLen": 5},
+		{"path": "sleep_time_ms", "max": 1000}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, Violations{
+		{Path: "value", Description: "value length must be at most 5"},
+		{Path: "sleep_time_ms", Description: "value must be less than or equal to 1000"},
+	}, validate(context.Background(), &pb_testproto.PingRequest{Value: "something", SleepTimeMs: 1001}))
+
+	_, err = ParseRules(&pb_testproto.PingRequest{}, []byte(`{"path": "value"}`))
+	assert.Error(t, err, "rules must be a list")
+}
@@ -8,6 +8,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/status"
 )
 
@@ -17,46 +18,128 @@ type validator interface {
 	Validate(all bool) error
 }
 
+// The validate interface of protoc-gen-validate for all the rules of a message, next to the legacy one.
+type validatorAll interface {
+	ValidateAll() error
+}
+
 // The validate interface prior to protoc-gen-validate v0.6.0.
 type validatorLegacy interface {
 	Validate() error
 }
 
-func validate(req interface{}) error {
-	switch v := req.(type) {
-	case validatorLegacy:
-		if err := v.Validate(); err != nil {
-			return status.Error(codes.InvalidArgument, err.Error())
+// validateGenerated checks all the rules of a message when it can, so that every field breaking them is reported,
+// or stops at the first broken rule with failFast.
+func validateGenerated(msg interface{}, failFast bool) error {
+	if failFast {
+		switch v := msg.(type) {
+		case validator:
+			return v.Validate(false)
+		case validatorLegacy:
+			return v.Validate()
+		case validatorAll:
+			return v.ValidateAll()
 		}
+		return nil
+	}
+	switch v := msg.(type) {
+	case validatorAll:
+		return v.ValidateAll()
 	case validator:
-		if err := v.Validate(false); err != nil {
-			return status.Error(codes.InvalidArgument, err.Error())
+		return v.Validate(true)
+	case validatorLegacy:
+		return v.Validate()
+	}
+	return nil
+}
+
+// validateMessage checks a message against its generated rules and the functions of the registry, the ones of
+// the method included for requests.
+func validateMessage(ctx context.Context, fullMethod string, msg interface{}, isRequest bool, o *options) error {
+	if o.skip(fullMethod, msg) {
+		return nil
+	}
+	var errs validationErrors
+	if err := validateGenerated(msg, o.failFast); err != nil {
+		if o.failFast {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	for _, f := range o.registry.funcs(fullMethod, msg, isRequest) {
+		if err := f(ctx, msg); err != nil {
+			if o.failFast {
+				return err
+			}
+			errs = append(errs, err)
 		}
 	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
+
+func validate(ctx context.Context, fullMethod string, req interface{}, o *options) error {
+	if err := validateMessage(ctx, fullMethod, req, true, o); err != nil {
+		return invalidRequest(ctx, fullMethod, req, err, o)
+	}
+	return nil
+}
+
+// validateSentResponse keeps the invalid responses of a server handler from leaving.
+func validateSentResponse(ctx context.Context, fullMethod string, resp interface{}, o *options) error {
+	if err := validateMessage(ctx, fullMethod, resp, false, o); err != nil {
+		grpclog.Errorf("grpc_validator: %s returned an invalid response: %v", fullMethod, err)
+		return transformError(ctx, fullMethod, status.Errorf(codes.Internal, "%s returned an invalid response", fullMethod), o)
+	}
+	return nil
+}
+
+// validateReceivedResponse rejects the invalid responses received by a client.
+func validateReceivedResponse(ctx context.Context, method string, resp interface{}, o *options) error {
+	if err := validateMessage(ctx, method, resp, false, o); err != nil {
+		return transformError(ctx, method, status.Errorf(codes.Internal, "invalid response received from %s: %v", method, err), o)
+	}
 	return nil
 }
 
 // UnaryServerInterceptor returns a new unary server interceptor that validates incoming messages.
 //
 // Invalid messages will be rejected with `InvalidArgument` before reaching any userspace handlers.
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := evaluateOptions(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if err := validate(req); err != nil {
+		if err := validate(ctx, info.FullMethod, req, o); err != nil {
 			return nil, err
 		}
-		return handler(ctx, req)
+		resp, err := handler(ctx, req)
+		if o.validateResponses && err == nil {
+			if err := validateSentResponse(ctx, info.FullMethod, resp, o); err != nil {
+				return nil, err
+			}
+		}
+		return resp, err
 	}
 }
 
 // UnaryClientInterceptor returns a new unary client interceptor that validates outgoing messages.
 //
 // Invalid messages will be rejected with `InvalidArgument` before sending the request to server.
-func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
-	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		if err := validate(req); err != nil {
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if err := validate(ctx, method, req, o); err != nil {
 			return err
 		}
-		return invoker(ctx, method, req, reply, cc, opts...)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		if o.validateResponses && err == nil {
+			return validateReceivedResponse(ctx, method, reply, o)
+		}
+		return err
 	}
 }
 
@@ -66,15 +149,33 @@ func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
 // type of the RPC. For `ServerStream` (1:m) requests, it will happen before reaching any userspace
 // handlers. For `ClientStream` (n:1) or `BidiStream` (n:m) RPCs, the messages will be rejected on
 // calls to `stream.Recv()`.
-func StreamServerInterceptor() grpc.StreamServerInterceptor {
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := evaluateOptions(opts)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		wrapper := &recvWrapper{stream}
+		wrapper := &recvWrapper{ServerStream: stream, info: info, opts: o}
 		return handler(srv, wrapper)
 	}
 }
 
+// StreamClientInterceptor returns a new streaming client interceptor that validates outgoing messages.
+//
+// Invalid messages will be rejected with `InvalidArgument` on calls to `stream.Send()`, before they are
+// sent to the server.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := evaluateOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		clientStream, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return &sendWrapper{ClientStream: clientStream, method: method, opts: o}, nil
+	}
+}
+
 type recvWrapper struct {
 	grpc.ServerStream
+	info *grpc.StreamServerInfo
+	opts *options
 }
 
 func (s *recvWrapper) RecvMsg(m interface{}) error {
@@ -82,9 +183,41 @@ func (s *recvWrapper) RecvMsg(m interface{}) error {
 		return err
 	}
 
-	if err := validate(m); err != nil {
+	if err := validate(s.Context(), s.info.FullMethod, m, s.opts); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (s *recvWrapper) SendMsg(m interface{}) error {
+	if s.opts.validateResponses {
+		if err := validateSentResponse(s.Context(), s.info.FullMethod, m, s.opts); err != nil {
+			return err
+		}
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+type sendWrapper struct {
+	grpc.ClientStream
+	method string
+	opts   *options
+}
+
+func (s *sendWrapper) SendMsg(m interface{}) error {
+	if err := validate(s.Context(), s.method, m, s.opts); err != nil {
+		return err
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *sendWrapper) RecvMsg(m interface{}) error {
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.opts.validateResponses {
+		return validateReceivedResponse(s.Context(), s.method, m, s.opts)
+	}
+	return nil
+}
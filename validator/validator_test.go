@@ -4,15 +4,20 @@
 package grpc_validator
 
 import (
+	"context"
+	"errors"
 	"io"
 	"math"
+	"strings"
 	"testing"
 
 	grpc_testing "github.com/grpc-ecosystem/go-grpc-middleware/testing"
 	pb_testproto "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	pb_validator "github.com/grpc-ecosystem/go-grpc-middleware/testing/validatortestproto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -29,11 +34,222 @@ var (
 )
 
 func TestValidateWrapper(t *testing.T) {
-	assert.NoError(t, validate(goodPing))
-	assert.Error(t, validate(badPing))
+	assert.NoError(t, validate(context.Background(), "", goodPing, defaultOptions))
+	assert.Error(t, validate(context.Background(), "", badPing, defaultOptions))
 
-	assert.NoError(t, validate(goodPingResponse))
-	assert.Error(t, validate(badPingResponse))
+	assert.NoError(t, validate(context.Background(), "", goodPingResponse, defaultOptions))
+	assert.Error(t, validate(context.Background(), "", badPingResponse, defaultOptions))
+}
+
+// pgvMessage and pgvAllMessage mimic the validate methods of the different versions of protoc-gen-validate, see
+// validatortestproto for the errors they actually generate.
+type pgvFieldError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e pgvFieldError) Field() string  { return e.field }
+func (e pgvFieldError) Reason() string { return e.reason }
+func (e pgvFieldError) Cause() error   { return e.cause }
+func (e pgvFieldError) Error() string  { return "invalid " + e.field + ": " + e.reason }
+
+type pgvMultiError []error
+
+func (m pgvMultiError) AllErrors() []error { return m }
+func (m pgvMultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type pgvMessage struct {
+	validatedAll bool
+}
+
+func (m *pgvMessage) Validate(all bool) error {
+	m.validatedAll = all
+	return pgvMultiError{
+		pgvFieldError{field: "email", reason: "value must be a valid email address"},
+		pgvFieldError{field: "name", reason: "value length must be at least 1 runes"},
+	}
+}
+
+type pgvAllMessage struct{}
+
+func (m *pgvAllMessage) Validate() error {
+	return pgvFieldError{field: "email", reason: "value must be a valid email address"}
+}
+
+func (m *pgvAllMessage) ValidateAll() error {
+	return pgvMultiError{
+		pgvFieldError{field: "email", reason: "value must be a valid email address"},
+		pgvFieldError{field: "name", reason: "value length must be at least 1 runes"},
+	}
+}
+
+func requireBadRequest(t *testing.T, err error) *errdetails.BadRequest {
+	st, ok := status.FromError(err)
+	require.True(t, ok, "the error must be a gRPC status")
+	require.Equal(t, codes.InvalidArgument, st.Code(), "gRPC status must be InvalidArgument")
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			return badRequest
+		}
+	}
+	require.Fail(t, "the status must have a BadRequest detail")
+	return nil
+}
+
+func validOuterMessage() *pb_validator.OuterMessage {
+	return &pb_validator.OuterMessage{ImportantString: "abc", Inner: &pb_validator.InnerMessage{SomeInteger: 1}}
+}
+
+func TestValidate_FieldViolations(t *testing.T) {
+	badInner := &pb_validator.InnerMessage{SomeInteger: 100}
+	for _, tcase := range []struct {
+		name      string
+		change    func(msg *pb_validator.OuterMessage)
+		violation *errdetails.BadRequest_FieldViolation
+	}{
+		{
+			name:      "field",
+			change:    func(msg *pb_validator.OuterMessage) { msg.ImportantString = "ABC" },
+			violation: &errdetails.BadRequest_FieldViolation{Field: "important_string", Description: `value does not match regex pattern "^[a-z]{2,5}$"`},
+		},
+		{
+			name:      "embedded message",
+			change:    func(msg *pb_validator.OuterMessage) { msg.Inner = badInner },
+			violation: &errdetails.BadRequest_FieldViolation{Field: "inner.some_integer", Description: "value must be inside range (0, 100)"},
+		},
+		{
+			name:      "repeated field",
+			change:    func(msg *pb_validator.OuterMessage) { msg.Inners = []*pb_validator.InnerMessage{msg.Inner, badInner} },
+			violation: &errdetails.BadRequest_FieldViolation{Field: "inners[1].some_integer", Description: "value must be inside range (0, 100)"},
+		},
+		{
+			name: "map field",
+			change: func(msg *pb_validator.OuterMessage) {
+				msg.NamedInners = map[string]*pb_validator.InnerMessage{"bad": badInner}
+			},
+			violation: &errdetails.BadRequest_FieldViolation{Field: "named_inners[bad].some_integer", Description: "value must be inside range (0, 100)"},
+		},
+		{
+			name:      "oneof field",
+			change:    func(msg *pb_validator.OuterMessage) { msg.Contact = &pb_validator.OuterMessage_Email{Email: "nope"} },
+			violation: &errdetails.BadRequest_FieldViolation{Field: "email", Description: "value must be a valid email address: mail: missing '@' or angle-addr"},
+		},
+		{
+			name: "oneof embedded message",
+			change: func(msg *pb_validator.OuterMessage) {
+				msg.Contact = &pb_validator.OuterMessage_Fallback{Fallback: badInner}
+			},
+			violation: &errdetails.BadRequest_FieldViolation{Field: "fallback.some_integer", Description: "value must be inside range (0, 100)"},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			msg := validOuterMessage()
+			require.NoError(t, validate(context.Background(), "", msg, defaultOptions))
+			tcase.change(msg)
+			badRequest := requireBadRequest(t, validate(context.Background(), "", msg, defaultOptions))
+			assert.Equal(t, []*errdetails.BadRequest_FieldViolation{tcase.violation}, badRequest.FieldViolations,
+				"fields must be named by their proto path")
+		})
+	}
+}
+
+func TestValidate_FieldViolationsOfAllErrors(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterType(&pb_validator.OuterMessage{}, func(ctx context.Context, msg interface{}) error {
+		return Violations{{Path: "inner.some_integer", Description: "value must be even"}}
+	})
+	msg := validOuterMessage()
+	msg.ImportantString = ""
+	badRequest := requireBadRequest(t, validate(context.Background(), "", msg, evaluateOptions([]Option{WithRegistry(registry)})))
+	assert.Equal(t, []*errdetails.BadRequest_FieldViolation{
+		{Field: "important_string", Description: `value does not match regex pattern "^[a-z]{2,5}$"`},
+		{Field: "inner.some_integer", Description: "value must be even"},
+	}, badRequest.FieldViolations)
+
+	m := &pgvMessage{}
+	requireBadRequest(t, validate(context.Background(), "", m, defaultOptions))
+	assert.True(t, m.validatedAll, "all the rules must be validated")
+}
+
+func TestValidate_PrefersValidateAll(t *testing.T) {
+	badRequest := requireBadRequest(t, validate(context.Background(), "", &pgvAllMessage{}, defaultOptions))
+	assert.Len(t, badRequest.FieldViolations, 2, "ValidateAll must be used when available")
+}
+
+func TestValidate_PlainErrorsHaveNoDetails(t *testing.T) {
+	st, _ := status.FromError(validate(context.Background(), "", badPing, defaultOptions))
+	assert.Equal(t, codes.InvalidArgument, st.Code(), "gRPC status must be InvalidArgument")
+	assert.Equal(t, "cannot sleep for more than 10s", st.Message())
+	assert.Empty(t, st.Details(), "errors that don't describe fields must not have details")
+}
+
+func TestValidate_FailFast(t *testing.T) {
+	o := evaluateOptions([]Option{WithFailFast()})
+	msg := &pgvMessage{}
+	requireBadRequest(t, validate(context.Background(), "", msg, o))
+	assert.False(t, msg.validatedAll, "Validate(false) must be used")
+
+	badRequest := requireBadRequest(t, validate(context.Background(), "", &pgvAllMessage{}, o))
+	assert.Len(t, badRequest.FieldViolations, 1, "the fail-fast Validate must be preferred to ValidateAll")
+
+	registry := NewRegistry()
+	registered := 0
+	for i := 0; i < 2; i++ {
+		registry.RegisterType(&pb_testproto.PingRequest{}, func(ctx context.Context, msg interface{}) error {
+			registered++
+			return errors.New("registered rule")
+		})
+	}
+	o = evaluateOptions([]Option{WithFailFast(), WithRegistry(registry)})
+	st, _ := status.FromError(validate(context.Background(), "", badPing, o))
+	assert.Equal(t, "cannot sleep for more than 10s", st.Message(), "only the first error must be reported")
+	assert.Equal(t, 0, registered, "the registry must not be called after a failure")
+
+	assert.Error(t, validate(context.Background(), "", goodPing, o))
+	assert.Equal(t, 1, registered, "the registry must stop at the first failing function")
+}
+
+func TestValidate_Skip(t *testing.T) {
+	o := evaluateOptions([]Option{
+		WithSkipMethods("/mwitkow.testproto.TestService/Ping"),
+		WithSkipMethods("/mwitkow.testproto.TestService/PingList"),
+		WithSkipMessages(&pgvAllMessage{}),
+	})
+	assert.NoError(t, validate(context.Background(), "/mwitkow.testproto.TestService/Ping", badPing, o))
+	assert.NoError(t, validate(context.Background(), "/mwitkow.testproto.TestService/PingList", badPing, o))
+	assert.NoError(t, validate(context.Background(), "/mwitkow.testproto.TestService/PingStream", &pgvAllMessage{}, o))
+	assert.Error(t, validate(context.Background(), "/mwitkow.testproto.TestService/PingStream", badPing, o))
+	assert.NoError(t, validateSentResponse(context.Background(), "/mwitkow.testproto.TestService/Ping", badPingResponse, o))
+}
+
+func TestValidate_ErrorTransform(t *testing.T) {
+	o := evaluateOptions([]Option{
+		WithCode(codes.FailedPrecondition),
+		WithMessageFunc(MessageTemplate("invalid request to {method}: {error}")),
+	})
+	err := validate(context.Background(), "/mwitkow.testproto.TestService/Ping", &pgvAllMessage{}, o)
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+	assert.Equal(t, "invalid request to /mwitkow.testproto.TestService/Ping: invalid email: value must be a valid email address; "+
+		"invalid name: value length must be at least 1 runes", st.Message())
+	assert.Len(t, st.Details(), 1, "the field violations must be kept")
+
+	o = evaluateOptions([]Option{WithErrorTransform(func(ctx context.Context, fullMethod string, err error) error {
+		return status.Errorf(codes.Aborted, "%s: %s", fullMethod, status.Convert(err).Message())
+	})})
+	st, _ = status.FromError(validate(context.Background(), "/mwitkow.testproto.TestService/Ping", badPing, o))
+	assert.Equal(t, codes.Aborted, st.Code())
+	assert.Equal(t, "/mwitkow.testproto.TestService/Ping: cannot sleep for more than 10s", st.Message())
+
+	err = validateReceivedResponse(context.Background(), "/mwitkow.testproto.TestService/Ping", badPingResponse, o)
+	assert.Equal(t, codes.Aborted, status.Code(err), "the errors of responses must be transformed too")
 }
 
 func TestValidatorTestSuite(t *testing.T) {
@@ -126,3 +342,167 @@ func (s *ClientValidatorTestSuite) TestInvalidErrors_Unary() {
 	assert.Error(s.T(), err, "error expected")
 	assert.Equal(s.T(), codes.InvalidArgument, status.Code(err), "gRPC status must be InvalidArgument")
 }
+
+// invalidResponseService answers with responses that fail validation.
+type invalidResponseService struct {
+	pb_testproto.TestServiceServer
+}
+
+func (s *invalidResponseService) Ping(ctx context.Context, ping *pb_testproto.PingRequest) (*pb_testproto.PingResponse, error) {
+	return badPingResponse, nil
+}
+
+func (s *invalidResponseService) PingList(ping *pb_testproto.PingRequest, stream pb_testproto.TestService_PingListServer) error {
+	if err := stream.Send(goodPingResponse); err != nil {
+		return err
+	}
+	return stream.Send(badPingResponse)
+}
+
+func (s *invalidResponseService) PingStream(stream pb_testproto.TestService_PingStreamServer) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return nil
+		}
+		if err := stream.Send(badPingResponse); err != nil {
+			return err
+		}
+	}
+}
+
+func TestResponseValidatorTestSuite(t *testing.T) {
+	s := &ResponseValidatorTestSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &invalidResponseService{&grpc_testing.TestPingService{T: t}},
+			ServerOpts: []grpc.ServerOption{
+				grpc.StreamInterceptor(StreamServerInterceptor(WithResponseValidation())),
+				grpc.UnaryInterceptor(UnaryServerInterceptor(WithResponseValidation())),
+			},
+		},
+	}
+	suite.Run(t, s)
+
+	cs := &ClientStreamValidatorTestSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			TestService: &invalidResponseService{&grpc_testing.TestPingService{T: t}},
+			ClientOpts: []grpc.DialOption{
+				grpc.WithUnaryInterceptor(UnaryClientInterceptor(WithResponseValidation())),
+				grpc.WithStreamInterceptor(StreamClientInterceptor(WithResponseValidation())),
+			},
+		},
+	}
+	suite.Run(t, cs)
+}
+
+type ResponseValidatorTestSuite struct {
+	*grpc_testing.InterceptorTestSuite
+}
+
+func (s *ResponseValidatorTestSuite) TestInvalidResponse_Unary() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "gRPC status must be Internal")
+}
+
+func (s *ResponseValidatorTestSuite) TestInvalidResponse_ServerStream() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "no error on stream establishment expected")
+	_, err = stream.Recv()
+	require.NoError(s.T(), err, "the valid response should be received")
+	_, err = stream.Recv()
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "gRPC status must be Internal")
+}
+
+type ClientStreamValidatorTestSuite struct {
+	*grpc_testing.InterceptorTestSuite
+}
+
+func (s *ClientStreamValidatorTestSuite) TestInvalidResponse_Unary() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "gRPC status must be Internal")
+}
+
+func (s *ClientStreamValidatorTestSuite) TestInvalidRequest_BidiStream() {
+	stream, err := s.Client.PingStream(s.SimpleCtx())
+	require.NoError(s.T(), err, "no error on stream establishment expected")
+
+	err = stream.Send(badPing)
+	assert.Equal(s.T(), codes.InvalidArgument, status.Code(err), "invalid messages must not be sent")
+	require.NoError(s.T(), stream.Send(goodPing), "valid messages should be sent")
+	_, err = stream.Recv()
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "invalid responses must be rejected")
+	assert.NoError(s.T(), stream.CloseSend(), "there should be no error closing the stream on send")
+}
+
+func (s *ClientStreamValidatorTestSuite) TestInvalidResponse_ServerStream() {
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "no error on stream establishment expected")
+	_, err = stream.Recv()
+	require.NoError(s.T(), err, "the valid response should be received")
+	_, err = stream.Recv()
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "invalid responses must be rejected")
+}
+
+func TestRegistryValidatorTestSuite(t *testing.T) {
+	registry := NewRegistry()
+	valueRule, err := Rules(&pb_testproto.PingRequest{}, Rule{Path: "value", Pattern: "^[a-z]*$"})
+	require.NoError(t, err)
+	registry.RegisterType(&pb_testproto.PingRequest{}, valueRule)
+	registry.RegisterMethod("/mwitkow.testproto.TestService/PingList", func(ctx context.Context, msg interface{}) error {
+		if msg.(*pb_testproto.PingRequest).SleepTimeMs > 0 {
+			return Violations{{Path: "sleep_time_ms", Description: "streams cannot sleep"}}
+		}
+		return nil
+	})
+	registry.RegisterType(&pb_testproto.PingResponse{}, func(ctx context.Context, msg interface{}) error {
+		if msg.(*pb_testproto.PingResponse).Value == "" {
+			return errors.New("value is required")
+		}
+		return nil
+	})
+
+	s := &RegistryValidatorTestSuite{
+		InterceptorTestSuite: &grpc_testing.InterceptorTestSuite{
+			ServerOpts: []grpc.ServerOption{
+				grpc.StreamInterceptor(StreamServerInterceptor(WithRegistry(registry))),
+				grpc.UnaryInterceptor(UnaryServerInterceptor(WithRegistry(registry), WithResponseValidation())),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type RegistryValidatorTestSuite struct {
+	*grpc_testing.InterceptorTestSuite
+}
+
+func (s *RegistryValidatorTestSuite) TestValidPasses_Unary() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	assert.NoError(s.T(), err, "no error expected")
+}
+
+func (s *RegistryValidatorTestSuite) TestTypeRules_Unary() {
+	_, err := s.Client.Ping(s.SimpleCtx(), &pb_testproto.PingRequest{Value: "Something", SleepTimeMs: 10001})
+	badRequest := requireBadRequest(s.T(), err)
+	assert.Contains(s.T(), status.Convert(err).Message(), "cannot sleep for more than 10s", "the errors of the generated validator must be kept")
+	assert.Equal(s.T(), []*errdetails.BadRequest_FieldViolation{
+		{Field: "value", Description: `value does not match regex pattern "^[a-z]*$"`},
+	}, badRequest.FieldViolations)
+}
+
+func (s *RegistryValidatorTestSuite) TestMethodRules_ServerStream() {
+	_, err := s.Client.Ping(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "the rules of a method must not apply to other methods")
+
+	stream, err := s.Client.PingList(s.SimpleCtx(), goodPing)
+	require.NoError(s.T(), err, "no error on stream creation")
+	_, err = stream.Recv()
+	badRequest := requireBadRequest(s.T(), err)
+	assert.Equal(s.T(), []*errdetails.BadRequest_FieldViolation{
+		{Field: "sleep_time_ms", Description: "streams cannot sleep"},
+	}, badRequest.FieldViolations)
+}
+
+func (s *RegistryValidatorTestSuite) TestTypeRules_Response() {
+	_, err := s.Client.Ping(s.SimpleCtx(), &pb_testproto.PingRequest{Value: ""})
+	assert.Equal(s.T(), codes.Internal, status.Code(err), "responses breaking the registered rules must be rejected")
+}